@@ -0,0 +1,61 @@
+package hdr
+
+// Premultiply multiplies in place the color channels of m by its alpha.
+// Images without alpha are left unchanged.
+func Premultiply(m Image) {
+	forEachAlpha(m, func(v, a float64) float64 {
+		return v * a
+	})
+}
+
+// Unpremultiply divides in place the alpha-premultiplied color channels of m by its alpha.
+// The color channels of transparent pixels are set to zero.
+// Images without alpha are left unchanged.
+func Unpremultiply(m Image) {
+	forEachAlpha(m, func(v, a float64) float64 {
+		if a == 0 {
+			return 0
+		}
+		return v / a
+	})
+}
+
+// forEachAlpha applies f to each color channel of the pixels of m with their alpha.
+func forEachAlpha(m Image, f func(v, a float64) float64) {
+	r := m.Bounds()
+
+	switch img := m.(type) {
+	case *RGBA:
+		forEachAlpha32(img.Pix, img.Stride, r.Dx(), r.Dy(), f)
+	case *XYZA:
+		forEachAlpha32(img.Pix, img.Stride, r.Dx(), r.Dy(), f)
+	case *RGBA64:
+		forEachAlpha64(img.Pix, img.Stride, r.Dx(), r.Dy(), f)
+	case *XYZA64:
+		forEachAlpha64(img.Pix, img.Stride, r.Dx(), r.Dy(), f)
+	}
+}
+
+func forEachAlpha32(pix []float32, stride, w, h int, f func(v, a float64) float64) {
+	for y := 0; y < h; y++ {
+		line := pix[y*stride : y*stride+4*w]
+		for i := 0; i < len(line); i += 4 {
+			a := float64(line[i+3])
+			line[i+0] = float32(f(float64(line[i+0]), a))
+			line[i+1] = float32(f(float64(line[i+1]), a))
+			line[i+2] = float32(f(float64(line[i+2]), a))
+		}
+	}
+}
+
+func forEachAlpha64(pix []float64, stride, w, h int, f func(v, a float64) float64) {
+	for y := 0; y < h; y++ {
+		line := pix[y*stride : y*stride+4*w]
+		for i := 0; i < len(line); i += 4 {
+			a := line[i+3]
+			line[i+0] = f(line[i+0], a)
+			line[i+1] = f(line[i+1], a)
+			line[i+2] = f(line[i+2], a)
+		}
+	}
+}
@@ -3,11 +3,16 @@ package cmd
 import (
 	"fmt"
 	"image"
+	"io"
 	"os"
 	"path/filepath"
 
 	"github.com/Xyzyx101/hdr"
 	"github.com/Xyzyx101/hdr/crad"
+	"github.com/Xyzyx101/hdr/exr"
+	"github.com/Xyzyx101/hdr/hdr10"
+	"github.com/Xyzyx101/hdr/hdrcolor"
+	"github.com/Xyzyx101/hdr/pfm"
 	"github.com/Xyzyx101/hdr/rgbe"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -22,10 +27,16 @@ var (
 		RunE:  convertAction,
 	}
 
-	toxyze bool
-	torgbe bool
-	tohdr  bool
-	tocrad bool
+	toxyze  bool
+	torgbe  bool
+	tohdr   bool
+	tocrad  bool
+	toexr   bool
+	topfm   bool
+	tohdr10 bool
+	tohlg   bool
+	toraw   bool
+	peak    float64
 )
 
 func init() {
@@ -33,6 +44,12 @@ func init() {
 	ConvertCommand.Flags().BoolVarP(&torgbe, "to-rgbe", "", false, "Converts to Radiance RGBE")
 	ConvertCommand.Flags().BoolVarP(&tohdr, "to-hdr", "", false, "Converts to Radiance RGBE/XYZE")
 	ConvertCommand.Flags().BoolVarP(&tocrad, "to-crad", "", false, "Converts to CRAD")
+	ConvertCommand.Flags().BoolVarP(&toexr, "to-exr", "", false, "Converts to OpenEXR")
+	ConvertCommand.Flags().BoolVarP(&topfm, "to-pfm", "", false, "Converts to PFM")
+	ConvertCommand.Flags().BoolVarP(&tohdr10, "to-hdr10", "", false, "Converts to HDR10 (16-bit PNG of Rec.2020 PQ signals)")
+	ConvertCommand.Flags().BoolVarP(&tohlg, "hlg", "", false, "Uses HLG instead of PQ for HDR10 output")
+	ConvertCommand.Flags().BoolVarP(&toraw, "raw", "", false, "Writes a raw 10-bit frame instead of a PNG for HDR10 output")
+	ConvertCommand.Flags().Float64VarP(&peak, "peak", "", hdr10.DefaultOptions.PeakLuminance, "Peak luminance in cd/m² for HDR10 output")
 }
 
 func convertAction(c *cobra.Command, args []string) error {
@@ -52,6 +69,17 @@ func convertAction(c *cobra.Command, args []string) error {
 	}
 	fmt.Printf("Read image (%dx%dp - %s) %s\n", m.Bounds().Dx(), m.Bounds().Dy(), fname, filepath.Base(args[0]))
 
+	// Keep Radiance metadata
+	header := rgbe.NewHeader()
+	if fname == "hdr" {
+		if _, err = fi.Seek(0, io.SeekStart); err != nil {
+			return errors.Wrap(err, "convert:")
+		}
+		if header, err = rgbe.DecodeHeader(fi); err != nil {
+			return errors.Wrap(err, "convert:")
+		}
+	}
+
 	fo, err := os.Create(args[1])
 	if err != nil {
 		return errors.Wrap(err, "convert:")
@@ -62,42 +90,37 @@ func convertAction(c *cobra.Command, args []string) error {
 	hdrm := m.(hdr.Image)
 	switch {
 	case toxyze:
-		rgbe.Encode(fo, toXYZ(hdrm))
+		header.Format = rgbe.FormatXYZE
+		err = rgbe.EncodeWithOptions(fo, hdrm, header)
 	case torgbe:
-		rgbe.Encode(fo, toRGB(hdrm))
+		header.Format = rgbe.FormatRGBE
+		err = rgbe.EncodeWithOptions(fo, hdrm, header)
 	case tohdr:
-		rgbe.Encode(fo, hdrm)
+		header.Format = "" // From the image color model
+		err = rgbe.EncodeWithOptions(fo, hdrm, header)
 	case tocrad:
-		crad.Encode(fo, hdrm)
+		err = crad.Encode(fo, hdrm)
+	case toexr:
+		err = exr.Encode(fo, hdrm)
+	case topfm:
+		err = pfm.Encode(fo, hdrm)
+	case tohdr10:
+		options := *hdr10.DefaultOptions
+		options.PeakLuminance = peak
+		if tohlg {
+			options.Transfer = hdrcolor.TransferHLG
+		}
+		if toraw {
+			err = hdr10.EncodeRaw(fo, hdrm, &options)
+		} else {
+			err = hdr10.EncodePNG(fo, hdrm, &options)
+		}
 	default:
 		return errors.New("convert: No converion flage provided")
 	}
-
-	return nil
-}
-
-func toRGB(m hdr.Image) hdr.Image {
-	d := m.Bounds()
-
-	new := hdr.NewRGB(d)
-	for y := 0; y < d.Dy(); y++ {
-		for x := 0; x < d.Dx(); x++ {
-			new.Set(x, y, m.HDRAt(x, y))
-		}
-	}
-
-	return new
-}
-
-func toXYZ(m hdr.Image) hdr.Image {
-	d := m.Bounds()
-
-	new := hdr.NewXYZ(d)
-	for y := 0; y < d.Dy(); y++ {
-		for x := 0; x < d.Dx(); x++ {
-			new.Set(x, y, m.HDRAt(x, y))
-		}
+	if err != nil {
+		return errors.Wrap(err, "convert:")
 	}
 
-	return new
+	return nil
 }
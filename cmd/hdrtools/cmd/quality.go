@@ -7,6 +7,9 @@ import (
 	"path/filepath"
 	"time"
 
+	// Import OpenEXR and PFM decoders
+	_ "github.com/Xyzyx101/hdr/exr"
+	_ "github.com/Xyzyx101/hdr/pfm"
 	"github.com/mdouchement/hdr"
 	// Import RGBE decoder
 	_ "github.com/mdouchement/hdr/crad"
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"image"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// VerifyCommand defines the command for checking the integrity of image files.
+	VerifyCommand = &cobra.Command{
+		Use:   "verify [flags] files...",
+		Short: "Verify image files",
+		Long:  "Decodes the image files and verifies their checksums (CRAD)",
+		RunE:  verifyAction,
+	}
+)
+
+func verifyAction(c *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return errors.New("verify: Invalid number of arguments")
+	}
+
+	failed := 0
+	for _, path := range args {
+		if err := verify(path); err != nil {
+			failed++
+			fmt.Printf("FAIL %s: %v\n", path, err)
+			continue
+		}
+		fmt.Printf("OK   %s\n", path)
+	}
+
+	if failed > 0 {
+		return errors.Errorf("verify: %d/%d files failed", failed, len(args))
+	}
+	return nil
+}
+
+func verify(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, _, err = image.Decode(f)
+	return err
+}
@@ -13,6 +13,7 @@ func main() {
 	}
 	c.AddCommand(cmd.QualityCommand)
 	c.AddCommand(cmd.ConvertCommand)
+	c.AddCommand(cmd.VerifyCommand)
 
 	if err := c.Execute(); err != nil {
 		fmt.Println(err)
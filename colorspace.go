@@ -0,0 +1,134 @@
+package hdr
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/Xyzyx101/hdr/hdrcolor"
+)
+
+// ColorSpace returns the color space of the RGB pixels of m, hdrcolor.Rec709 for untagged images.
+// It returns nil for XYZ images, whose colors are absolute.
+func ColorSpace(m image.Image) *hdrcolor.ColorSpace {
+	var cs *hdrcolor.ColorSpace
+
+	switch img := m.(type) {
+	case *RGB:
+		cs = img.Space
+	case *RGB64:
+		cs = img.Space
+	case *RGBA:
+		cs = img.Space
+	case *RGBA64:
+		cs = img.Space
+	case *RGB16F:
+		cs = img.Space
+	case *XYZ, *XYZ64, *XYZA, *XYZA64, *XYZ16F:
+		return nil
+	}
+
+	if cs == nil {
+		return hdrcolor.Rec709
+	}
+	return cs
+}
+
+// ConvertColorSpace returns a copy of m whose linear pixels are converted to the color space cs.
+// The result is a *RGB, or a *RGBA when m has an alpha channel, tagged with cs.
+func ConvertColorSpace(m Image, cs *hdrcolor.ColorSpace) Image {
+	from := ColorSpace(m)
+	if from == nil {
+		return transform(m, cs.FromXYZ(), true, false, cs)
+	}
+	return transform(m, from.ConversionMatrix(cs), false, false, cs)
+}
+
+// ConvertColorSpaceWithCAT is like ConvertColorSpace with a chromatic adaptation from the white point
+// of the color space of m (D65 for XYZ images) to the white point of cs with the transform cat.
+func ConvertColorSpaceWithCAT(m Image, cs *hdrcolor.ColorSpace, cat *hdrcolor.CAT) Image {
+	from := ColorSpace(m)
+	if from == nil {
+		mat := cs.FromXYZ().Mul(hdrcolor.AdaptationMatrix(cat, hdrcolor.D65, cs.White))
+		return transform(m, mat, true, false, cs)
+	}
+	return transform(m, from.AdaptedConversionMatrix(cs, cat), false, false, cs)
+}
+
+// ChromaticAdaptation returns a copy of m whose colors seen under the white point src
+// are adapted to the white point dst with the transform cat (e.g. from hdrcolor.IlluminantA to hdrcolor.D65).
+// The result has the same color model as m, RGB images keeping their color space.
+func ChromaticAdaptation(m Image, cat *hdrcolor.CAT, src, dst hdrcolor.Chromaticity) Image {
+	mat := hdrcolor.AdaptationMatrix(cat, src, dst)
+
+	cs := ColorSpace(m)
+	if cs == nil {
+		return transform(m, mat, true, true, nil)
+	}
+	return transform(m, cs.FromXYZ().Mul(mat).Mul(cs.ToXYZ()), false, false, cs)
+}
+
+// transform returns a copy of m whose pixels are multiplied by mat.
+// Pixels are read in XYZ when xyzIn is true and the result is an XYZ image when xyzOut is true,
+// otherwise an RGB image tagged with cs. The alpha channel is kept.
+func transform(m Image, mat hdrcolor.Matrix, xyzIn, xyzOut bool, cs *hdrcolor.ColorSpace) Image {
+	b := m.Bounds()
+	alpha := m.ColorModel() == hdrcolor.RGBAModel || m.ColorModel() == hdrcolor.XYZAModel
+
+	var dst Image
+	switch {
+	case xyzOut && alpha:
+		dst = NewXYZA(b)
+	case xyzOut:
+		dst = NewXYZ(b)
+	case alpha:
+		dst = &RGBA{Pix: make([]float32, 4*b.Dx()*b.Dy()), Stride: 4 * b.Dx(), Rect: b, Space: cs}
+	default:
+		dst = &RGB{Pix: make([]float32, 3*b.Dx()*b.Dy()), Stride: 3 * b.Dx(), Rect: b, Space: cs}
+	}
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := m.HDRAt(x, y)
+
+			var c0, c1, c2, a float64
+			if xyzIn {
+				c0, c1, c2, a = c.HDRXYZA()
+			} else {
+				c0, c1, c2, a = linearRGBA(c)
+			}
+			c0, c1, c2 = mat.Apply(c0, c1, c2)
+
+			switch img := dst.(type) {
+			case *RGB:
+				img.SetRGB(x, y, hdrcolor.RGB{R: c0, G: c1, B: c2})
+			case *RGBA:
+				img.SetRGBA(x, y, hdrcolor.RGBA{R: c0, G: c1, B: c2, A: a})
+			case *XYZ:
+				img.SetXYZ(x, y, hdrcolor.XYZ{X: c0, Y: c1, Z: c2})
+			case *XYZA:
+				img.SetXYZA(x, y, hdrcolor.XYZA{X: c0, Y: c1, Z: c2, A: a})
+			}
+		}
+	}
+
+	return dst
+}
+
+// linearRGBA returns the linear values of c in its own color space,
+// HDRRGBA converting tagged colors to Rec.709.
+func linearRGBA(c hdrcolor.Color) (r, g, b, a float64) {
+	switch c1 := c.(type) {
+	case hdrcolor.RGB:
+		return c1.R, c1.G, c1.B, 1
+	case hdrcolor.RGBA:
+		return c1.R, c1.G, c1.B, c1.A
+	}
+	return c.HDRRGBA()
+}
+
+// rgbaIn converts c to the linear values of the color space cs, keeping its alpha.
+func rgbaIn(cs *hdrcolor.ColorSpace, c color.Color) hdrcolor.RGBA {
+	c1 := hdrcolor.RGBAModel.Convert(c).(hdrcolor.RGBA)
+	rgb := cs.From(hdrcolor.RGB{R: c1.R, G: c1.G, B: c1.B, Space: c1.Space})
+	return hdrcolor.RGBA{R: rgb.R, G: rgb.G, B: rgb.B, A: c1.A, Space: cs}
+}
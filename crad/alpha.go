@@ -0,0 +1,37 @@
+package crad
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/Xyzyx101/hdr/format"
+)
+
+// alphaToBytes converts the alpha a to a channel of size bytes:
+// a float (4), a half float (2) or an 8-bit value within [0, 1] (1).
+func alphaToBytes(a float64, size int) []byte {
+	b := make([]byte, size)
+
+	switch size {
+	case 4:
+		binary.LittleEndian.PutUint32(b, math.Float32bits(float32(a)))
+	case 2:
+		binary.LittleEndian.PutUint16(b, format.FloatToHalf(float32(a)))
+	default:
+		b[0] = uint8(math.Max(0, math.Min(1, a))*255 + 0.5)
+	}
+
+	return b
+}
+
+// alphaFromBytes converts an alpha channel to its value.
+func alphaFromBytes(b []byte) float64 {
+	switch len(b) {
+	case 4:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	case 2:
+		return float64(format.HalfToFloat(binary.LittleEndian.Uint16(b)))
+	default:
+		return float64(b[0]) / 255
+	}
+}
@@ -0,0 +1,48 @@
+package crad
+
+import (
+	"bytes"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+// newChecksum returns the hash of the header's checksum, nil when the raster has no checksum.
+func newChecksum(h *Header) hash.Hash32 {
+	if h.Checksum == ChecksumCRC32 {
+		return crc32.NewIEEE()
+	}
+	return nil
+}
+
+// A checksumWriter hashes the uncompressed raster and appends its checksum
+// (big endian) at the end of the compressed stream.
+type checksumWriter struct {
+	compresserWriter
+	sum hash.Hash32
+}
+
+func (w *checksumWriter) Write(p []byte) (int, error) {
+	w.sum.Write(p)
+	return w.compresserWriter.Write(p)
+}
+
+func (w *checksumWriter) Close() error {
+	if _, err := w.compresserWriter.Write(w.sum.Sum(nil)); err != nil {
+		return err
+	}
+	return w.compresserWriter.Close()
+}
+
+// verifyChecksum reads the checksum following the raster from r and compares it with sum.
+func verifyChecksum(r io.Reader, sum hash.Hash32) error {
+	expected := make([]byte, sum.Size())
+	if _, err := io.ReadFull(r, expected); err != nil {
+		return readError(err)
+	}
+
+	if !bytes.Equal(expected, sum.Sum(nil)) {
+		return CorruptionError("checksum mismatch")
+	}
+	return nil
+}
@@ -1,8 +1,13 @@
 package crad
 
 import (
+	"compress/flate"
 	"compress/gzip"
+	"compress/lzw"
+	"compress/zlib"
 	"io"
+	"io/ioutil"
+	"strings"
 )
 
 type compresserWriter interface {
@@ -10,22 +15,163 @@ type compresserWriter interface {
 	Flush() error
 }
 
-func newCompresserWriter(w io.Writer, h *Header) (c compresserWriter) {
-	switch h.Compression {
-	case CompressionGzip:
-		c = gzip.NewWriter(w)
+// A compresser creates the writers and readers of a compression method.
+type compresser struct {
+	newWriter func(w io.Writer, level int) (io.WriteCloser, error)
+	newReader func(r io.Reader) (io.ReadCloser, error)
+}
+
+var compressers = map[string]compresser{}
+
+// RegisterCompression registers a compression method usable as Header.Compression.
+// newWriter returns a writer, at the given Header.CompressionLevel, whose Close terminates
+// the compressed stream without closing w. newReader returns a reader of the decompressed stream.
+// RegisterCompression is not safe for concurrent use and is typically called in an init function.
+func RegisterCompression(name string,
+	newWriter func(w io.Writer, level int) (io.WriteCloser, error),
+	newReader func(r io.Reader) (io.ReadCloser, error)) {
+	compressers[name] = compresser{
+		newWriter: newWriter,
+		newReader: newReader,
+	}
+}
+
+func init() {
+	RegisterCompression(CompressionNone,
+		func(w io.Writer, level int) (io.WriteCloser, error) {
+			return nopWriteCloser{w}, nil
+		},
+		func(r io.Reader) (io.ReadCloser, error) {
+			return ioutil.NopCloser(r), nil
+		})
+
+	RegisterCompression(CompressionGzip,
+		func(w io.Writer, level int) (io.WriteCloser, error) {
+			wc, err := gzip.NewWriterLevel(w, flateLevel(level))
+			if err != nil {
+				return nil, UnsupportedError("compression level")
+			}
+			return wc, nil
+		},
+		func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		})
+
+	RegisterCompression(CompressionDeflate,
+		func(w io.Writer, level int) (io.WriteCloser, error) {
+			wc, err := flate.NewWriter(w, flateLevel(level))
+			if err != nil {
+				return nil, UnsupportedError("compression level")
+			}
+			return wc, nil
+		},
+		func(r io.Reader) (io.ReadCloser, error) {
+			return flate.NewReader(r), nil
+		})
+
+	RegisterCompression(CompressionZlib,
+		func(w io.Writer, level int) (io.WriteCloser, error) {
+			wc, err := zlib.NewWriterLevel(w, flateLevel(level))
+			if err != nil {
+				return nil, UnsupportedError("compression level")
+			}
+			return wc, nil
+		},
+		func(r io.Reader) (io.ReadCloser, error) {
+			return zlib.NewReader(r)
+		})
+
+	RegisterCompression(CompressionLZW,
+		func(w io.Writer, level int) (io.WriteCloser, error) {
+			return lzw.NewWriter(w, lzw.LSB, 8), nil
+		},
+		func(r io.Reader) (io.ReadCloser, error) {
+			return lzw.NewReader(r, lzw.LSB, 8), nil
+		})
+}
+
+// flateLevel converts a header compression level to a flate level,
+// 0 being the default compression.
+func flateLevel(level int) int {
+	if level == 0 {
+		return flate.DefaultCompression
+	}
+	return level
+}
+
+func newCompresserWriter(w io.Writer, h *Header) (compresserWriter, error) {
+	c, ok := compressers[h.Compression]
+	if !ok {
+		return nil, UnsupportedError("compression")
+	}
+
+	wc, err := c.newWriter(w, h.CompressionLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	cw, ok := wc.(compresserWriter)
+	if !ok {
+		cw = nopFlusher{wc}
 	}
 
-	return
+	if sum := newChecksum(h); sum != nil {
+		return &checksumWriter{cw, sum}, nil
+	}
+	return cw, nil
+}
+
+func newCompresserReader(r io.Reader, h *Header) (io.ReadCloser, error) {
+	c, ok := compressers[h.Compression]
+	if !ok {
+		return nil, UnsupportedError("compression")
+	}
+
+	rc, err := c.newReader(r)
+	if err != nil {
+		return nil, readError(err)
+	}
+	return rc, nil
 }
 
-func newCompresserReader(r io.Reader, h *Header) (c io.ReadCloser) {
-	var err error
-	switch h.Compression {
-	case CompressionGzip:
-		c, err = gzip.NewReader(r)
+// readError converts the errors of truncated or corrupted raster to a CorruptionError.
+// Other errors, like the I/O errors of the underlying reader, are returned unchanged.
+func readError(err error) error {
+	if _, ok := err.(flate.CorruptInputError); ok {
+		return CorruptionError("invalid compressed data")
+	}
+
+	switch err {
+	case io.EOF, io.ErrUnexpectedEOF:
+		return CorruptionError("not enough data")
+	case gzip.ErrChecksum, gzip.ErrHeader, zlib.ErrChecksum, zlib.ErrHeader, zlib.ErrDictionary:
+		return CorruptionError("invalid compressed data")
 	}
-	check(err)
 
-	return
+	// The LZW decompressor errors are not exported (e.g. invalid code)
+	if strings.HasPrefix(err.Error(), "lzw: ") {
+		return CorruptionError("invalid compressed data")
+	}
+	return err
 }
+
+// headerError converts the errors of truncated header to a FormatError.
+func headerError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return FormatError("not enough data")
+	}
+	return err
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+func (nopWriteCloser) Flush() error { return nil }
+
+type nopFlusher struct {
+	io.WriteCloser
+}
+
+func (nopFlusher) Flush() error { return nil }
@@ -2,6 +2,9 @@ package crad
 
 const (
 	header = "#?CRAD"
+	// version is the current version of the format.
+	// Version 1 files (without version in the header) have no checksum.
+	version = 2
 )
 
 const (
@@ -24,18 +27,127 @@ const (
 	// RasterModeSeparately for separately pixel's color positioning
 	RasterModeSeparately = "separately"
 
+	// FilterNone for unfiltered scanlines
+	FilterNone = "none"
+	// FilterSub predicts a byte from the left pixel
+	FilterSub = "sub"
+	// FilterUp predicts a byte from the upper pixel
+	FilterUp = "up"
+	// FilterAverage predicts a byte from the average of the left and upper pixels
+	FilterAverage = "average"
+	// FilterPaeth predicts a byte from the left, upper or upper-left pixel (PNG Paeth predictor)
+	FilterPaeth = "paeth"
+	// FilterXOR stores the bits of a value XORed with the left pixel, suited to floats
+	FilterXOR = "xor"
+	// FilterAdaptive selects the best filter for each scanline
+	FilterAdaptive = "adaptive"
+
+	// CompressionNone for uncompressed raster
+	CompressionNone = "none"
 	// CompressionGzip for gzip compression
 	CompressionGzip = "gzip"
+	// CompressionDeflate for raw deflate compression
+	CompressionDeflate = "deflate"
+	// CompressionZlib for zlib compression
+	CompressionZlib = "zlib"
+	// CompressionLZW for LZW compression (LSB order, 8-bit literals)
+	CompressionLZW = "lzw"
+
+	// ChecksumNone for raster without checksum
+	ChecksumNone = "none"
+	// ChecksumCRC32 for a CRC-32 (IEEE) of the uncompressed raster
+	ChecksumCRC32 = "crc32"
 )
 
 // A Header handles all image properties.
 type Header struct {
+	// Version is the version of the format, 1 when missing. It is set by the encoder.
+	Version     int    `json:"version,omitempty"`
 	Width       int    `json:"width"`
 	Height      int    `json:"height"`
 	Depth       int    `json:"depth"`
 	Format      string `json:"format"`
 	RasterMode  string `json:"raster_mode"`
 	Compression string `json:"compression"`
+	// Alpha adds an alpha channel after the color channels of the format.
+	Alpha bool `json:"alpha,omitempty"`
+	// Filter is the scanline predictor applied before the compression.
+	Filter string `json:"filter,omitempty"`
+	// TileWidth and TileHeight, when set, split the raster in tiles compressed independently.
+	// EncodeWithOptions splits images without tile size in strips of 64 rows.
+	TileWidth  int `json:"tile_width,omitempty"`
+	TileHeight int `json:"tile_height,omitempty"`
+	// Offsets is the offset table of the tiles, from the beginning of the raster.
+	// The last offset is the end of the raster. It is computed by the encoder.
+	Offsets []int64 `json:"offsets,omitempty"`
+	// CompressionLevel is the level of the compression method when supported (e.g. -2 to 9 for gzip, deflate and zlib).
+	// 0 selects the default level. It is not needed by the decoder.
+	CompressionLevel int `json:"compression_level,omitempty"`
+	// Checksum is the checksum of each compressed stream (the whole raster or each tile), verified by the decoder.
+	Checksum string `json:"checksum,omitempty"`
+	// Metadata holds free-form properties of the image (e.g. capture settings, color space, software).
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+const (
+	// maxSize avoids huge allocations on corrupted headers.
+	maxSize = 1 << 30
+	// maxTiles avoids huge offset tables.
+	maxTiles = 1 << 20
+	// stripHeight is the height of the strips of images encoded without tile size.
+	stripHeight = 64
+)
+
+// validate checks that the header describes a supported image.
+func (h *Header) validate() error {
+	if h.Version > version {
+		return UnsupportedError("version")
+	}
+
+	if h.Width <= 0 || h.Height <= 0 || h.Width > maxSize/h.Height {
+		return FormatError("invalid dimensions")
+	}
+
+	switch h.Format {
+	case FormatRGBE, FormatXYZE, FormatRGB, FormatXYZ, FormatLogLuv:
+	default:
+		return UnsupportedError("format")
+	}
+
+	switch h.RasterMode {
+	case RasterModeNormal, RasterModeSeparately:
+	default:
+		return UnsupportedError("raster mode")
+	}
+
+	if h.tiled() {
+		if err := h.validateTiles(); err != nil {
+			return err
+		}
+	}
+
+	if _, ok := filterTypes[h.Filter]; !ok && h.Filter != "" && h.Filter != FilterAdaptive {
+		return UnsupportedError("filter")
+	}
+
+	if _, ok := compressers[h.Compression]; !ok {
+		return UnsupportedError("compression")
+	}
+
+	switch h.Checksum {
+	case "", ChecksumNone, ChecksumCRC32:
+	default:
+		return UnsupportedError("checksum")
+	}
+
+	switch {
+	case h.Depth == 32:
+	case h.Depth == 16 && (h.Format == FormatRGB || h.Format == FormatXYZ):
+	default:
+		return UnsupportedError("depth")
+	}
+
+	return nil
 }
 
 var (
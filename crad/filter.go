@@ -0,0 +1,173 @@
+package crad
+
+// Scanline predictors, inspired by PNG filters.
+// A filtered scanline starts with one byte holding its filter type.
+// Predictions use the same byte of the left pixel (bpp bytes before)
+// and of the previous scanline.
+
+// Filter types stored in the raster.
+const (
+	ftNone byte = iota
+	ftSub
+	ftUp
+	ftAverage
+	ftPaeth
+	ftXOR
+	nbOfFilterTypes
+)
+
+var filterTypes = map[string]byte{
+	FilterNone:    ftNone,
+	FilterSub:     ftSub,
+	FilterUp:      ftUp,
+	FilterAverage: ftAverage,
+	FilterPaeth:   ftPaeth,
+	FilterXOR:     ftXOR,
+}
+
+// filtered reports whether the scanlines of the header are prefixed by a filter type.
+func (h *Header) filtered() bool {
+	return h.Filter != "" && h.Filter != FilterNone
+}
+
+// A filterer applies the header filter on successive scanlines.
+type filterer struct {
+	ft   byte // Filter type, nbOfFilterTypes for adaptive filtering
+	bpp  int
+	prev []byte
+	buf  [][]byte // Filtered scanline for each filter type
+}
+
+func newFilterer(h *Header, bpp, length int) *filterer {
+	f := &filterer{
+		ft:   nbOfFilterTypes,
+		bpp:  bpp,
+		prev: make([]byte, length),
+		buf:  make([][]byte, nbOfFilterTypes),
+	}
+	if ft, ok := filterTypes[h.Filter]; ok {
+		f.ft = ft
+	}
+
+	for i := range f.buf {
+		f.buf[i] = make([]byte, 1+length)
+		f.buf[i][0] = byte(i)
+	}
+
+	return f
+}
+
+// filter returns the filtered cur scanline, prefixed by its filter type.
+func (f *filterer) filter(cur []byte) []byte {
+	defer copy(f.prev, cur)
+
+	if f.ft != nbOfFilterTypes {
+		filterScanline(f.ft, f.buf[f.ft][1:], cur, f.prev, f.bpp)
+		return f.buf[f.ft]
+	}
+
+	// Adaptive filtering: the filter type with the smallest sum of absolute
+	// differences is the most likely to compress well.
+	best := ftNone
+	min := -1
+	for ft := ftNone; ft < nbOfFilterTypes; ft++ {
+		filterScanline(ft, f.buf[ft][1:], cur, f.prev, f.bpp)
+
+		sum := 0
+		for _, b := range f.buf[ft][1:] {
+			if b < 128 {
+				sum += int(b)
+			} else {
+				sum += 256 - int(b)
+			}
+		}
+
+		if min < 0 || sum < min {
+			best, min = ft, sum
+		}
+	}
+
+	return f.buf[best]
+}
+
+// filterScanline writes in dst the cur scanline filtered with the filter type ft.
+func filterScanline(ft byte, dst, cur, prev []byte, bpp int) {
+	for i := range cur {
+		var a, c byte // Left and upper-left bytes
+		if i >= bpp {
+			a = cur[i-bpp]
+			c = prev[i-bpp]
+		}
+		b := prev[i] // Upper byte
+
+		switch ft {
+		case ftNone:
+			dst[i] = cur[i]
+		case ftSub:
+			dst[i] = cur[i] - a
+		case ftUp:
+			dst[i] = cur[i] - b
+		case ftAverage:
+			dst[i] = cur[i] - byte((int(a)+int(b))/2)
+		case ftPaeth:
+			dst[i] = cur[i] - paeth(a, b, c)
+		case ftXOR:
+			dst[i] = cur[i] ^ a
+		}
+	}
+}
+
+// unfilterScanline restores in place the cur scanline filtered with the filter type ft.
+func unfilterScanline(ft byte, cur, prev []byte, bpp int) error {
+	if ft >= nbOfFilterTypes {
+		return CorruptionError("invalid filter type")
+	}
+
+	for i := range cur {
+		var a, c byte // Left and upper-left bytes
+		if i >= bpp {
+			a = cur[i-bpp]
+			c = prev[i-bpp]
+		}
+		b := prev[i] // Upper byte
+
+		switch ft {
+		case ftSub:
+			cur[i] += a
+		case ftUp:
+			cur[i] += b
+		case ftAverage:
+			cur[i] += byte((int(a) + int(b)) / 2)
+		case ftPaeth:
+			cur[i] += paeth(a, b, c)
+		case ftXOR:
+			cur[i] ^= a
+		}
+	}
+
+	return nil
+}
+
+// paeth returns the closest of a, b and c to the linear prediction a + b - c.
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa := abs(p - int(a))
+	pb := abs(p - int(b))
+	pc := abs(p - int(c))
+
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	default:
+		return c
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -2,6 +2,7 @@ package crad
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"image"
 	"io"
@@ -9,16 +10,18 @@ import (
 	"github.com/Xyzyx101/hdr"
 	"github.com/Xyzyx101/hdr/format"
 	"github.com/Xyzyx101/hdr/hdrcolor"
+	"github.com/Xyzyx101/hdr/util"
 )
 
 type decoder struct {
-	r           io.Reader
-	cr          io.ReadCloser
-	h           *Header
-	config      image.Config
-	convert     func(pixel []byte) (float64, float64, float64)
-	nbOfchannel int
-	channelSize int
+	r            io.Reader
+	h            *Header
+	rasterOffset int64 // Position of the raster from the beginning of the image
+	tiles        []image.Rectangle
+	config       image.Config
+	convert      func(pixel []byte) (float64, float64, float64)
+	nbOfchannel  int
+	channelSize  int
 }
 
 func newDecoder(r io.Reader) (*decoder, error) {
@@ -37,7 +40,7 @@ func newDecoder(r io.Reader) (*decoder, error) {
 func (d *decoder) parseHeader() error {
 	magic, err := readUntil(d.r, '\n')
 	if err != nil {
-		return err
+		return headerError(err)
 	}
 	if magic != header {
 		return FormatError("format not compatible")
@@ -45,13 +48,28 @@ func (d *decoder) parseHeader() error {
 
 	h, err := readUntil(d.r, '\n')
 	if err != nil {
-		return err
+		return headerError(err)
 	}
+	d.rasterOffset = int64(len(magic) + 1 + len(h) + 1)
 
 	if err := json.Unmarshal([]byte(h), d.h); err != nil {
+		return FormatError("invalid header")
+	}
+	if err := d.h.validate(); err != nil {
 		return err
 	}
-	d.cr = newCompresserReader(d.r, d.h)
+	if d.h.tiled() {
+		if err := d.h.validateOffsets(); err != nil {
+			return err
+		}
+		d.tiles = d.h.tiles()
+	}
+
+	// Depth of RGB/XYZ channels
+	fromBytes := format.FromBytes
+	if d.h.Depth == 16 {
+		fromBytes = format.FromHalfBytes
+	}
 
 	switch d.h.Format {
 	case FormatRGBE:
@@ -69,18 +87,18 @@ func (d *decoder) parseHeader() error {
 			return format.FromRadianceBytes(p[0], p[1], p[2], p[3], 1)
 		}
 	case FormatRGB:
-		d.channelSize = 4
+		d.channelSize = d.h.Depth / 8
 		d.nbOfchannel = 3
 		d.config.ColorModel = hdrcolor.RGBModel
 		d.convert = func(p []byte) (float64, float64, float64) {
-			return format.FromBytes(p)
+			return fromBytes(p)
 		}
 	case FormatXYZ:
 		d.config.ColorModel = hdrcolor.XYZModel
-		d.channelSize = 4
+		d.channelSize = d.h.Depth / 8
 		d.nbOfchannel = 3
 		d.convert = func(p []byte) (float64, float64, float64) {
-			return format.FromBytes(p)
+			return fromBytes(p)
 		}
 	case FormatLogLuv:
 		d.config.ColorModel = hdrcolor.XYZModel
@@ -90,6 +108,17 @@ func (d *decoder) parseHeader() error {
 			return format.LogLuvToXYZ(p[0], p[1], p[2], p[3])
 		}
 	}
+
+	// Alpha channel of the same size than the color channels
+	if d.h.Alpha {
+		d.nbOfchannel++
+		if d.config.ColorModel == hdrcolor.RGBModel {
+			d.config.ColorModel = hdrcolor.RGBAModel
+		} else {
+			d.config.ColorModel = hdrcolor.XYZAModel
+		}
+	}
+
 	d.config.Width = d.h.Width
 	d.config.Height = d.h.Height
 
@@ -100,54 +129,196 @@ func (d *decoder) parseHeader() error {
 // Pixels parser                        //
 //--------------------------------------//
 
-func (d *decoder) decode(dst image.Image, y int, scanline []byte) {
-	size := d.nbOfchannel * d.channelSize
+// set sets the pixel (x, y) of dst from its bytes.
+func (d *decoder) set(dst hdr.Image, x, y int, pixel []byte) {
+	b0, b1, b2 := d.convert(pixel)
+
+	switch img := dst.(type) {
+	case *hdr.RGB:
+		img.SetRGB(x, y, hdrcolor.RGB{R: b0, G: b1, B: b2})
+	case *hdr.XYZ:
+		img.SetXYZ(x, y, hdrcolor.XYZ{X: b0, Y: b1, Z: b2})
+	case *hdr.RGBA:
+		img.SetRGBA(x, y, hdrcolor.RGBA{R: b0, G: b1, B: b2, A: d.alpha(pixel)})
+	case *hdr.XYZA:
+		img.SetXYZA(x, y, hdrcolor.XYZA{X: b0, Y: b1, Z: b2, A: d.alpha(pixel)})
+	}
+}
 
-	for x := 0; x < d.config.Width; x++ {
-		b0, b1, b2 := d.convert(scanline[x*size : x*size+size])
+// alpha returns the alpha of the pixel, stored in the last channel.
+func (d *decoder) alpha(pixel []byte) float64 {
+	return alphaFromBytes(pixel[len(pixel)-d.channelSize:])
+}
+
+// decode sets the pixels of the scanline from (x0, y) of width pixels, within the bounds of dst.
+func (d *decoder) decode(dst hdr.Image, x0, y, width int, scanline []byte) {
+	size := d.nbOfchannel * d.channelSize
+	r := dst.Bounds()
 
-		switch d.h.Format {
-		case FormatRGBE:
-			fallthrough
-		case FormatRGB:
-			img := dst.(*hdr.RGB)
-			img.SetRGB(x, y, hdrcolor.RGB{R: b0, G: b1, B: b2})
-		case FormatXYZE:
-			fallthrough
-		case FormatLogLuv:
-			fallthrough
-		case FormatXYZ:
-			img := dst.(*hdr.XYZ)
-			img.SetXYZ(x, y, hdrcolor.XYZ{X: b0, Y: b1, Z: b2})
+	for x := 0; x < width; x++ {
+		if x0+x < r.Min.X || x0+x >= r.Max.X {
+			continue
 		}
+
+		d.set(dst, x0+x, y, scanline[x*size:x*size+size])
 	}
 }
 
-func (d *decoder) decodeSeparately(dst image.Image, y int, scanline []byte) {
-	for x := 0; x < d.config.Width; x++ {
-		pixel := make([]byte, 0, d.nbOfchannel*d.channelSize)
+// decodeSeparately is like decode for scanlines in separately raster mode.
+func (d *decoder) decodeSeparately(dst hdr.Image, x0, y, width int, scanline []byte) {
+	pixel := make([]byte, d.nbOfchannel*d.channelSize)
+	r := dst.Bounds()
+
+	for x := 0; x < width; x++ {
+		if x0+x < r.Min.X || x0+x >= r.Max.X {
+			continue
+		}
+
 		for c := 0; c < d.nbOfchannel; c++ {
-			pos := x*d.channelSize + c*d.channelSize*d.config.Width
-			pixel = append(pixel, scanline[pos:pos+d.channelSize]...)
+			pos := x*d.channelSize + c*d.channelSize*width
+			copy(pixel[c*d.channelSize:], scanline[pos:pos+d.channelSize])
 		}
 
-		b0, b1, b2 := d.convert(pixel)
+		d.set(dst, x0+x, y, pixel)
+	}
+}
+
+// bpp returns the distance in bytes between the same byte of two adjacent pixels in a scanline.
+func (d *decoder) bpp() int {
+	if d.h.RasterMode == RasterModeSeparately {
+		return d.channelSize
+	}
+	return d.nbOfchannel * d.channelSize
+}
+
+// newImage allocates an image of the given bounds matching the format.
+func (d *decoder) newImage(r image.Rectangle) hdr.Image {
+	switch d.config.ColorModel {
+	case hdrcolor.RGBModel:
+		return hdr.NewRGB(r)
+	case hdrcolor.RGBAModel:
+		return hdr.NewRGBA(r)
+	case hdrcolor.XYZAModel:
+		return hdr.NewXYZA(r)
+	}
+	return hdr.NewXYZ(r)
+}
 
-		switch d.h.Format {
-		case FormatRGBE:
-			fallthrough
-		case FormatRGB:
-			img := dst.(*hdr.RGB)
-			img.SetRGB(x, y, hdrcolor.RGB{R: b0, G: b1, B: b2})
-		case FormatXYZE:
-			fallthrough
-		case FormatLogLuv:
-			fallthrough
-		case FormatXYZ:
-			img := dst.(*hdr.XYZ)
-			img.SetXYZ(x, y, hdrcolor.XYZ{X: b0, Y: b1, Z: b2})
+// readStream decodes the scanlines of the area rect from the compressed stream r.
+// Only the pixels within the bounds of dst are set.
+// The checksum is verified once the whole stream is read.
+func (d *decoder) readStream(dst hdr.Image, r io.Reader, rect image.Rectangle) error {
+	cr, err := newCompresserReader(r, d.h)
+	if err != nil {
+		return err
+	}
+	defer cr.Close()
+
+	sum := newChecksum(d.h)
+	partial := !d.h.tiled() && rect.Max.Y < d.config.Height
+
+	length := rect.Dx() * d.nbOfchannel * d.channelSize
+	raw := make([]byte, length)
+	scanline := raw
+	var prev []byte
+	if d.h.filtered() {
+		// Filter type and previous scanline for predictions
+		raw = make([]byte, 1+length)
+		scanline = raw[1:]
+		prev = make([]byte, length)
+	}
+
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		if _, err = io.ReadFull(cr, raw); err != nil {
+			return readError(err)
+		}
+		if sum != nil {
+			sum.Write(raw)
+		}
+
+		if prev != nil {
+			if err = unfilterScanline(raw[0], scanline, prev, d.bpp()); err != nil {
+				return err
+			}
+			copy(prev, scanline)
+		}
+
+		switch d.h.RasterMode {
+		case RasterModeNormal:
+			d.decode(dst, rect.Min.X, y, rect.Dx(), scanline)
+		case RasterModeSeparately:
+			d.decodeSeparately(dst, rect.Min.X, y, rect.Dx(), scanline)
 		}
 	}
+
+	if partial {
+		return nil
+	}
+
+	if sum != nil {
+		if err = verifyChecksum(cr, sum); err != nil {
+			return err
+		}
+	}
+
+	// Reaching the end of the stream verifies the compression trailer.
+	// Version 1 files may have no trailer, so a truncated trailer is accepted.
+	if d.h.Version < 2 {
+		if _, err = cr.Read(raw[:1]); err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return readError(err)
+		}
+		return nil
+	}
+
+	switch _, err = io.ReadFull(cr, raw[:1]); err {
+	case io.EOF:
+		return nil
+	case nil:
+		return CorruptionError("trailing data")
+	default:
+		return readError(err)
+	}
+}
+
+// readChunk reads the compressed data of the tile i from r positioned at the beginning of the tile.
+func (d *decoder) readChunk(r io.Reader, i int) ([]byte, error) {
+	size := d.h.Offsets[i+1] - d.h.Offsets[i]
+
+	// The buffer grows with the read data, not with the size given by the header.
+	buf := &bytes.Buffer{}
+	if _, err := buf.ReadFrom(io.LimitReader(r, size)); err != nil {
+		return nil, err
+	}
+	if int64(buf.Len()) != size {
+		return nil, CorruptionError("not enough data")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decodeChunks concurrently decodes the given tiles compressed data.
+func (d *decoder) decodeChunks(dst hdr.Image, chunks map[int][]byte) error {
+	indexes := make([]int, 0, len(chunks))
+	for i := range d.tiles {
+		if _, ok := chunks[i]; ok {
+			indexes = append(indexes, i)
+		}
+	}
+
+	// Tiles are disjoint, so they are decoded in the same image.
+	errs := make([]error, len(indexes))
+	<-util.ParallelN(len(indexes), func(j int) {
+		i := indexes[j]
+		errs[j] = d.readStream(dst, bytes.NewReader(chunks[i]), d.tiles[i])
+	})
+
+	// The first error in file order
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 //--------------------------------------//
@@ -164,47 +335,103 @@ func DecodeConfig(r io.Reader) (image.Config, error) {
 	return d.config, nil
 }
 
+// DecodeHeader returns the header of a CRAD image, with its metadata, without decoding the entire image.
+func DecodeHeader(r io.Reader) (*Header, error) {
+	d, err := newDecoder(r)
+	if err != nil {
+		return nil, err
+	}
+	return d.h, nil
+}
+
 // Decode reads a HDR image from r and returns an image.Image.
-func Decode(r io.Reader) (img image.Image, err error) {
+// Images with an alpha channel are decoded in a *hdr.RGBA or a *hdr.XYZA.
+// The tiles of tiled images are decoded concurrently.
+func Decode(r io.Reader) (image.Image, error) {
+	img, _, err := DecodeWithHeader(r)
+	return img, err
+}
+
+// DecodeWithHeader reads a HDR image from r and returns an image.Image and its header.
+func DecodeWithHeader(r io.Reader) (image.Image, *Header, error) {
+	d, err := newDecoder(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rect := image.Rect(0, 0, d.config.Width, d.config.Height)
+	img := d.newImage(rect)
+
+	if !d.h.tiled() {
+		if err := d.readStream(img, d.r, rect); err != nil {
+			return nil, nil, err
+		}
+		return img, d.h, nil
+	}
+
+	chunks := map[int][]byte{}
+	for i := range d.tiles {
+		if chunks[i], err = d.readChunk(d.r, i); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := d.decodeChunks(img, chunks); err != nil {
+		return nil, nil, err
+	}
+
+	return img, d.h, nil
+}
+
+// DecodeRegion reads the area rect of a CRAD image from r and returns an image.Image
+// whose bounds are rect clipped to the image bounds.
+// Only the tiles overlapping rect are read from tiled images (and decoded concurrently),
+// others images are read up to the bottom of rect.
+func DecodeRegion(r io.ReadSeeker, rect image.Rectangle) (image.Image, error) {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+
 	d, err := newDecoder(r)
 	if err != nil {
 		return nil, err
 	}
 
-	imgRect := image.Rect(0, 0, d.config.Width, d.config.Height)
-	switch d.h.Format {
-	case FormatRGBE:
-		fallthrough
-	case FormatRGB:
-		img = hdr.NewRGB(imgRect)
-	case FormatXYZE:
-		fallthrough
-	case FormatLogLuv:
-		fallthrough
-	case FormatXYZ:
-		img = hdr.NewXYZ(imgRect)
-	default:
-		err = UnsupportedError("image mode")
-		return
+	bounds := image.Rect(0, 0, d.config.Width, d.config.Height)
+	rect = rect.Intersect(bounds)
+	img := d.newImage(rect)
+	if rect.Empty() {
+		return img, nil
 	}
 
-	scanline := make([]byte, d.config.Width*d.nbOfchannel*d.channelSize)
+	if !d.h.tiled() {
+		bounds.Max.Y = rect.Max.Y
+		if err := d.readStream(img, d.r, bounds); err != nil {
+			return nil, err
+		}
+		return img, nil
+	}
 
-	for y := 0; y < d.config.Height; y++ {
-		_, err = io.ReadFull(d.cr, scanline)
-		if err != nil {
-			return
+	chunks := map[int][]byte{}
+	for i, tile := range d.tiles {
+		if !tile.Overlaps(rect) {
+			continue
 		}
 
-		switch d.h.RasterMode {
-		case RasterModeNormal:
-			d.decode(img, y, scanline)
-		case RasterModeSeparately:
-			d.decodeSeparately(img, y, scanline)
+		if _, err := r.Seek(start+d.rasterOffset+d.h.Offsets[i], io.SeekStart); err != nil {
+			return nil, err
+		}
+		if chunks[i], err = d.readChunk(r, i); err != nil {
+			return nil, err
 		}
 	}
 
-	return
+	if err := d.decodeChunks(img, chunks); err != nil {
+		return nil, err
+	}
+
+	return img, nil
 }
 
 func init() {
@@ -0,0 +1,95 @@
+package crad
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/Xyzyx101/hdr"
+)
+
+// A ScanlineWriter encodes a CRAD image one scanline at a time,
+// so that huge images can be produced with a bounded memory.
+type ScanlineWriter struct {
+	e  *encoder
+	wb *bufio.Writer
+	wc compresserWriter
+	f  *filterer
+	y  int
+}
+
+// NewScanlineWriter writes the header h to w and returns a ScanlineWriter expecting the first scanline.
+// The Width, Height and Format of h must be set, other missing properties get their default value.
+func NewScanlineWriter(w io.Writer, h *Header) (*ScanlineWriter, error) {
+	if h == nil {
+		return nil, FormatError("missing header")
+	}
+
+	hc := *h // Defaults only apply to this image
+	e := newEncoder(w, nil, &hc)
+
+	if err := e.configureHeader(); err != nil {
+		return nil, err
+	}
+	if e.h.tiled() {
+		// The offset table of the header is known once all the tiles are compressed.
+		return nil, UnsupportedError("streaming tiled layout")
+	}
+
+	if err := e.writeHeader(); err != nil {
+		return nil, err
+	}
+
+	wb := bufio.NewWriter(w)
+	wc, err := newCompresserWriter(wb, e.h)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ScanlineWriter{
+		e:  e,
+		wb: wb,
+		wc: wc,
+		f:  e.newFilterer(e.h.Width),
+	}, nil
+}
+
+// Y returns the index of the next scanline to be written.
+func (s *ScanlineWriter) Y() int {
+	return s.y
+}
+
+// WriteScanline encodes the first scanline of row as the next scanline of the image.
+// row must be at least as wide as the image, any color model is converted to the header's format.
+func (s *ScanlineWriter) WriteScanline(row hdr.Image) error {
+	if s.y >= s.e.h.Height {
+		return InternalError("too many scanlines")
+	}
+
+	r := row.Bounds()
+	if r.Dx() < s.e.h.Width || r.Empty() {
+		return InternalError("scanline too small")
+	}
+
+	if err := s.e.writeScanline(s.wc, row, r.Min.X, r.Min.Y, s.e.h.Width, s.f); err != nil {
+		return err
+	}
+
+	s.y++
+	return nil
+}
+
+// Close terminates the compressed stream and flushes the buffered data to the underlying writer.
+// It returns an error if some scanlines have not been written.
+func (s *ScanlineWriter) Close() error {
+	if err := s.wc.Close(); err != nil {
+		return err
+	}
+	if err := s.wb.Flush(); err != nil {
+		return err
+	}
+
+	if s.y != s.e.h.Height {
+		return InternalError("missing scanlines")
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package crad
+
+import "image"
+
+// tiled reports whether the raster is stored in independently compressed tiles.
+func (h *Header) tiled() bool {
+	return h.TileWidth != 0 || h.TileHeight != 0
+}
+
+// tiles returns the bounds of the tiles in file order (row by row).
+func (h *Header) tiles() []image.Rectangle {
+	nx := (h.Width + h.TileWidth - 1) / h.TileWidth
+	ny := (h.Height + h.TileHeight - 1) / h.TileHeight
+	bounds := image.Rect(0, 0, h.Width, h.Height)
+
+	tiles := make([]image.Rectangle, 0, nx*ny)
+	for ty := 0; ty < ny; ty++ {
+		for tx := 0; tx < nx; tx++ {
+			tile := image.Rect(tx*h.TileWidth, ty*h.TileHeight, (tx+1)*h.TileWidth, (ty+1)*h.TileHeight)
+			tiles = append(tiles, tile.Intersect(bounds))
+		}
+	}
+
+	return tiles
+}
+
+// validateTiles checks the tile size of a tiled header.
+func (h *Header) validateTiles() error {
+	if h.TileWidth <= 0 || h.TileHeight <= 0 {
+		return FormatError("invalid tile size")
+	}
+
+	nx := (h.Width + h.TileWidth - 1) / h.TileWidth
+	ny := (h.Height + h.TileHeight - 1) / h.TileHeight
+	if nx > maxTiles/ny {
+		return UnsupportedError("too many tiles")
+	}
+
+	return nil
+}
+
+// validateOffsets checks the offset table of a tiled header.
+func (h *Header) validateOffsets() error {
+	tiles := h.tiles()
+	if len(h.Offsets) != len(tiles)+1 || h.Offsets[0] != 0 {
+		return FormatError("invalid offset table")
+	}
+
+	for i := 1; i < len(h.Offsets); i++ {
+		if h.Offsets[i] < h.Offsets[i-1] {
+			return FormatError("invalid offset table")
+		}
+	}
+
+	return nil
+}
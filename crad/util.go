@@ -47,6 +47,13 @@ func (e FormatError) Error() string {
 	return "crad: invalid format: " + string(e)
 }
 
+// A CorruptionError reports that the raster of a CRAD image is truncated or corrupted.
+type CorruptionError string
+
+func (e CorruptionError) Error() string {
+	return "crad: corrupted data: " + string(e)
+}
+
 // An UnsupportedError reports that the input uses a valid but
 // unimplemented feature.
 type UnsupportedError string
@@ -61,9 +68,3 @@ type InternalError string
 func (e InternalError) Error() string {
 	return "crad: internal error: " + string(e)
 }
-
-func check(err error) {
-	if err != nil {
-		panic(err)
-	}
-}
@@ -1,21 +1,24 @@
 package crad
 
 import (
-	"bufio"
+	"bytes"
 	"encoding/json"
 	"io"
 
 	"github.com/Xyzyx101/hdr"
 	"github.com/Xyzyx101/hdr/format"
+	"github.com/Xyzyx101/hdr/hdrcolor"
+	"github.com/Xyzyx101/hdr/util"
 )
 
 type encoder struct {
 	w           io.Writer
 	m           hdr.Image
 	h           *Header
-	bytesAt     func(x, y int) []byte
+	bytesAt     func(c hdrcolor.Color) []byte
 	nbOfchannel int
 	channelSize int
+	writeline   []byte
 }
 
 func newEncoder(w io.Writer, m hdr.Image, h *Header) *encoder {
@@ -32,6 +35,10 @@ func newEncoder(w io.Writer, m hdr.Image, h *Header) *encoder {
 
 func (e *encoder) configureHeader() error {
 	// Header - Defaults
+	e.h.Version = version
+	if e.h.Checksum == "" {
+		e.h.Checksum = ChecksumCRC32
+	}
 	if e.h.Depth == 0 {
 		e.h.Depth = 32
 	}
@@ -41,60 +48,95 @@ func (e *encoder) configureHeader() error {
 	if e.h.RasterMode == "" {
 		e.h.RasterMode = RasterModeNormal
 	}
-	if e.h.Format == "" {
+	if e.h.Format == "" && e.m != nil {
 		switch e.m.(type) {
-		case *hdr.RGB:
+		case *hdr.RGB, *hdr.RGB16F:
+			e.h.Format = FormatRGBE
+		case *hdr.XYZ, *hdr.XYZ16F:
+			e.h.Format = FormatXYZE
+		case *hdr.RGBA:
 			e.h.Format = FormatRGBE
-		case *hdr.XYZ:
+			e.h.Alpha = true
+		case *hdr.XYZA:
 			e.h.Format = FormatXYZE
+			e.h.Alpha = true
 		default:
 			return UnsupportedError("color model")
 		}
 	}
 
+	// Header - Size
+	if e.m != nil {
+		d := e.m.Bounds().Size()
+		e.h.Width = d.X
+		e.h.Height = d.Y
+	}
+
+	// Header - Strips of full width or height
+	if e.h.TileWidth == 0 && e.h.TileHeight > 0 {
+		e.h.TileWidth = e.h.Width
+	}
+	if e.h.TileHeight == 0 && e.h.TileWidth > 0 {
+		e.h.TileHeight = e.h.Height
+	}
+
+	if err := e.h.validate(); err != nil {
+		return err
+	}
+
+	// Header - Depth of RGB/XYZ channels
+	toBytes := format.ToBytes
+	if e.h.Depth == 16 {
+		toBytes = format.ToHalfBytes
+	}
+
 	// Header - Format
 	switch e.h.Format {
 	case FormatRGBE:
 		e.channelSize = 1
 		e.nbOfchannel = 4
-		e.bytesAt = func(x, y int) []byte {
-			r, g, b, _ := e.m.HDRAt(x, y).HDRRGBA()
+		e.bytesAt = func(c hdrcolor.Color) []byte {
+			r, g, b, _ := c.HDRRGBA()
 			return format.ToRadianceBytes(r, g, b)
 		}
 	case FormatXYZE:
 		e.channelSize = 1
 		e.nbOfchannel = 4
-		e.bytesAt = func(x, y int) []byte {
-			xx, yy, zz, _ := e.m.HDRAt(x, y).HDRXYZA()
+		e.bytesAt = func(c hdrcolor.Color) []byte {
+			xx, yy, zz, _ := c.HDRXYZA()
 			return format.ToRadianceBytes(xx, yy, zz)
 		}
 	case FormatRGB:
-		e.channelSize = 4
+		e.channelSize = e.h.Depth / 8
 		e.nbOfchannel = 3
-		e.bytesAt = func(x, y int) []byte {
-			r, g, b, _ := e.m.HDRAt(x, y).HDRRGBA()
-			return format.ToBytes(r, g, b)
+		e.bytesAt = func(c hdrcolor.Color) []byte {
+			r, g, b, _ := c.HDRRGBA()
+			return toBytes(r, g, b)
 		}
 	case FormatXYZ:
-		e.channelSize = 4
+		e.channelSize = e.h.Depth / 8
 		e.nbOfchannel = 3
-		e.bytesAt = func(x, y int) []byte {
-			xx, yy, zz, _ := e.m.HDRAt(x, y).HDRXYZA()
-			return format.ToBytes(xx, yy, zz)
+		e.bytesAt = func(c hdrcolor.Color) []byte {
+			xx, yy, zz, _ := c.HDRXYZA()
+			return toBytes(xx, yy, zz)
 		}
 	case FormatLogLuv:
 		e.channelSize = 1
 		e.nbOfchannel = 4
-		e.bytesAt = func(x, y int) []byte {
-			xx, yy, zz, _ := e.m.HDRAt(x, y).HDRXYZA()
+		e.bytesAt = func(c hdrcolor.Color) []byte {
+			xx, yy, zz, _ := c.HDRXYZA()
 			return format.XYZToLogLuv(xx, yy, zz)
 		}
 	}
 
-	// Header - Size
-	d := e.m.Bounds().Size()
-	e.h.Width = d.X
-	e.h.Height = d.Y
+	// Header - Alpha channel of the same size than the color channels
+	if e.h.Alpha {
+		bytesAt := e.bytesAt
+		e.nbOfchannel++
+		e.bytesAt = func(c hdrcolor.Color) []byte {
+			return append(bytesAt(c), alphaToBytes(hdrcolor.Alpha(c), e.channelSize)...)
+		}
+	}
 
 	return nil
 }
@@ -119,49 +161,97 @@ func (e *encoder) writeHeader() error {
 // Pixels writer                        //
 //--------------------------------------//
 
-func (e *encoder) encode(w compresserWriter) error {
-	d := e.m.Bounds().Size()
+// encodeTiles concurrently compresses each tile and returns them with their offset table.
+func (e *encoder) encodeTiles() ([][]byte, []int64, error) {
+	o := e.m.Bounds().Min
+	tiles := e.h.tiles()
+	chunks := make([][]byte, len(tiles))
+	errs := make([]error, len(tiles))
 
-	var err error
-	for y := 0; y < d.Y; y++ {
-		for x := 0; x < d.X; x++ {
-			pixel := e.bytesAt(x, y)
-			_, err = w.Write(pixel)
+	<-util.ParallelN(len(tiles), func(i int) {
+		te := *e // Scanline buffer is not shared between goroutines
+		te.writeline = nil
+		tile := tiles[i]
 
-			if err != nil {
-				return err
+		buf := &bytes.Buffer{}
+		w, err := newCompresserWriter(buf, e.h)
+		if err != nil {
+			errs[i] = err
+			return
+		}
+
+		f := te.newFilterer(tile.Dx())
+		for y := tile.Min.Y; y < tile.Max.Y; y++ {
+			if err := te.writeScanline(w, e.m, o.X+tile.Min.X, o.Y+y, tile.Dx(), f); err != nil {
+				errs[i] = err
+				return
 			}
 		}
+
+		errs[i] = w.Close()
+		chunks[i] = buf.Bytes()
+	})
+
+	offsets := make([]int64, 1, len(tiles)+1)
+	for i, chunk := range chunks {
+		if errs[i] != nil {
+			return nil, nil, errs[i]
+		}
+		offsets = append(offsets, offsets[i]+int64(len(chunk)))
 	}
 
-	return w.Flush()
+	return chunks, offsets, nil
 }
 
-func (e *encoder) encodeSeparately(w compresserWriter) error {
-	d := e.m.Bounds().Size()
-	writeline := make([]byte, d.X*e.nbOfchannel*e.channelSize)
-
-	var err error
-	for y := 0; y < d.Y; y++ {
-		for x := 0; x < d.X; x++ {
-			// Separate colors
-			pixel := e.bytesAt(x, y)
-
-			for c := 0; c < e.nbOfchannel; c++ {
-				pos := x*e.channelSize + c*e.channelSize*e.h.Width
-				for i := 0; i < e.channelSize; i++ {
-					writeline[pos+i] = pixel[c*e.channelSize+i]
-				}
-			}
+// writeScanline writes width pixels of m from (x0, y) according to the raster mode and the filterer f.
+// A nil f writes an unfiltered scanline.
+func (e *encoder) writeScanline(w io.Writer, m hdr.Image, x0, y, width int, f *filterer) error {
+	size := e.nbOfchannel * e.channelSize
+
+	if len(e.writeline) != width*size {
+		e.writeline = make([]byte, width*size)
+	}
+
+	for x := 0; x < width; x++ {
+		pixel := e.bytesAt(m.HDRAt(x0+x, y))
+
+		if e.h.RasterMode == RasterModeNormal {
+			copy(e.writeline[x*size:], pixel)
+			continue
 		}
-		_, err = w.Write(writeline)
 
-		if err != nil {
-			return err
+		// Separate colors
+		for c := 0; c < e.nbOfchannel; c++ {
+			pos := x*e.channelSize + c*e.channelSize*width
+			for i := 0; i < e.channelSize; i++ {
+				e.writeline[pos+i] = pixel[c*e.channelSize+i]
+			}
 		}
 	}
 
-	return w.Flush()
+	if f == nil {
+		_, err := w.Write(e.writeline)
+		return err
+	}
+
+	_, err := w.Write(f.filter(e.writeline))
+	return err
+}
+
+// newFilterer returns the filterer of scanlines of width pixels, nil when the header has no filter.
+func (e *encoder) newFilterer(width int) *filterer {
+	if !e.h.filtered() {
+		return nil
+	}
+	return newFilterer(e.h, e.bpp(), width*e.nbOfchannel*e.channelSize)
+}
+
+// bpp returns the distance in bytes between the same byte of two adjacent pixels in a scanline.
+func (e *encoder) bpp() int {
+	if e.h.RasterMode == RasterModeSeparately {
+		return e.channelSize
+	}
+	return e.nbOfchannel * e.channelSize
 }
 
 // Encode writes the Image m to w in CRAD format.
@@ -170,34 +260,42 @@ func Encode(w io.Writer, m hdr.Image) error {
 }
 
 // EncodeWithOptions writes the Image m to w in CRAD format.
+// The image is stored in independently compressed tiles processed concurrently, strips of 64 rows
+// when the tile size of h is not set. A single tile dimension gives strips of the full image width or height.
+// h is not modified so a header (e.g. Mode1 to Mode5) can be shared by concurrent encodings.
 func EncodeWithOptions(w io.Writer, m hdr.Image, h *Header) error {
-	e := newEncoder(w, m, h)
+	if h == nil {
+		return FormatError("missing header")
+	}
+
+	hc := *h // Format, alpha and strip size inferred from m only apply to this image
+	if hc.TileWidth == 0 && hc.TileHeight == 0 {
+		hc.TileHeight = stripHeight
+		if dy := m.Bounds().Dy(); dy > 0 && dy < stripHeight {
+			hc.TileHeight = dy
+		}
+	}
+	e := newEncoder(w, m, &hc)
 
 	if err := e.configureHeader(); err != nil {
 		return err
 	}
 
-	if err := e.writeHeader(); err != nil {
+	chunks, offsets, err := e.encodeTiles()
+	if err != nil {
 		return err
 	}
+	e.h.Offsets = offsets
 
-	wb := bufio.NewWriter(e.w)
-	wc := newCompresserWriter(wb, e.h)
-	defer wc.Close()
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
 
-	// Write raster
-	switch e.h.RasterMode {
-	case RasterModeNormal:
-		if err := e.encode(wc); err != nil {
+	for _, chunk := range chunks {
+		if _, err := e.w.Write(chunk); err != nil {
 			return err
 		}
-	case RasterModeSeparately:
-		if err := e.encodeSeparately(wc); err != nil {
-			return err
-		}
-	default:
-		return UnsupportedError("raster mode")
 	}
 
-	return wb.Flush()
+	return nil
 }
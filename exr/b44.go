@@ -0,0 +1,152 @@
+package exr
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/Xyzyx101/hdr/format"
+)
+
+// B44 compression: HALF channels are split in blocks of 4 by 4 pixels,
+// each block is stored in 14 bytes (or 3 bytes for B44A flat blocks).
+// Other channels are stored uncompressed.
+
+func b44Decompress(channels []Channel, packed []byte, width, lines int) ([]byte, error) {
+	planes := make([][]uint16, len(channels))
+
+	for i, c := range channels {
+		n := c.PixelType.size() / 2 // Number of uint16 per pixel
+		plane := make([]uint16, width*lines*n)
+		planes[i] = plane
+
+		if c.PixelType != PixelTypeHalf {
+			if len(packed) < 2*len(plane) {
+				return nil, FormatError("not enough b44 data")
+			}
+			for j := range plane {
+				plane[j] = binary.LittleEndian.Uint16(packed[2*j:])
+			}
+			packed = packed[2*len(plane):]
+			continue
+		}
+
+		var s [16]uint16
+		for y := 0; y < lines; y += 4 {
+			for x := 0; x < width; x += 4 {
+				if len(packed) < 3 {
+					return nil, FormatError("not enough b44 data")
+				}
+				if packed[2] >= 13<<2 {
+					unpack3(packed, &s)
+					packed = packed[3:]
+				} else {
+					if len(packed) < 14 {
+						return nil, FormatError("not enough b44 data")
+					}
+					unpack14(packed, &s)
+					packed = packed[14:]
+				}
+
+				if c.PLinear {
+					for k := range s {
+						s[k] = b44ExpTable[s[k]]
+					}
+				}
+
+				for dy := 0; dy < 4 && y+dy < lines; dy++ {
+					for dx := 0; dx < 4 && x+dx < width; dx++ {
+						plane[(y+dy)*width+x+dx] = s[dy*4+dx]
+					}
+				}
+			}
+		}
+	}
+
+	// Restore the scanlines interleaving
+	raw := make([]byte, 0, 2*len(planes)*width*lines)
+	for y := 0; y < lines; y++ {
+		for i, c := range channels {
+			n := width * c.PixelType.size() / 2
+			for _, v := range planes[i][y*n : y*n+n] {
+				raw = append(raw, byte(v), byte(v>>8))
+			}
+		}
+	}
+
+	return raw, nil
+}
+
+// unpack14 decodes a 4 by 4 pixels block stored in 14 bytes.
+func unpack14(b []byte, s *[16]uint16) {
+	shift := uint(b[2] >> 2)
+	bias := uint32(0x20) << shift
+
+	delta := func(v byte) uint32 {
+		return uint32(v&0x3f)<<shift - bias
+	}
+	next := func(prev uint16, v byte) uint16 {
+		return uint16(uint32(prev) + delta(v))
+	}
+
+	s[0] = uint16(b[0])<<8 | uint16(b[1])
+
+	s[4] = next(s[0], b[2]<<4|b[3]>>4)
+	s[8] = next(s[4], b[3]<<2|b[4]>>6)
+	s[12] = next(s[8], b[4])
+
+	s[1] = next(s[0], b[5]>>2)
+	s[5] = next(s[4], b[5]<<4|b[6]>>4)
+	s[9] = next(s[8], b[6]<<2|b[7]>>6)
+	s[13] = next(s[12], b[7])
+
+	s[2] = next(s[1], b[8]>>2)
+	s[6] = next(s[5], b[8]<<4|b[9]>>4)
+	s[10] = next(s[9], b[9]<<2|b[10]>>6)
+	s[14] = next(s[13], b[10])
+
+	s[3] = next(s[2], b[11]>>2)
+	s[7] = next(s[6], b[11]<<4|b[12]>>4)
+	s[11] = next(s[10], b[12]<<2|b[13]>>6)
+	s[15] = next(s[14], b[13])
+
+	for i := range s {
+		s[i] = fromOrdered(s[i])
+	}
+}
+
+// unpack3 decodes a flat 4 by 4 pixels block stored in 3 bytes.
+func unpack3(b []byte, s *[16]uint16) {
+	v := fromOrdered(uint16(b[0])<<8 | uint16(b[1]))
+	for i := range s {
+		s[i] = v
+	}
+}
+
+// fromOrdered converts the ordered representation used by B44 to half bits.
+func fromOrdered(v uint16) uint16 {
+	if v&0x8000 != 0 {
+		return v & 0x7fff
+	}
+	return ^v
+}
+
+// b44ExpTable converts perceptually linear (logarithmic) halfs to linear halfs.
+var b44ExpTable = func() []uint16 {
+	table := make([]uint16, 1<<16)
+	max := 8 * math.Log(65504)
+
+	for i := range table {
+		f := float64(format.HalfToFloat(uint16(i)))
+
+		switch {
+		case math.IsNaN(f) || math.IsInf(f, 0):
+			table[i] = 0
+		case f >= max:
+			table[i] = 0x7bff // Max half
+		default:
+			table[i] = format.FloatToHalf(float32(math.Exp(f / 8)))
+		}
+	}
+
+	return table
+}()
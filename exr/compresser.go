@@ -0,0 +1,215 @@
+package exr
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+)
+
+// compress packs raw block data with the given compression method.
+// The raw data is returned when the compression does not reduce the size,
+// as required by the specification.
+func compress(c Compression, raw []byte) ([]byte, error) {
+	var packed []byte
+
+	switch c {
+	case CompressionNone:
+		return raw, nil
+	case CompressionRLE:
+		packed = rleCompress(predict(interleave(raw)))
+	case CompressionZIPS, CompressionZIP:
+		buf := &bytes.Buffer{}
+		zw := zlib.NewWriter(buf)
+		if _, err := zw.Write(predict(interleave(raw))); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		packed = buf.Bytes()
+	default:
+		return nil, UnsupportedError("compression")
+	}
+
+	if len(packed) >= len(raw) {
+		return raw, nil
+	}
+	return packed, nil
+}
+
+// decompress unpacks the data of a block of width by lines pixels.
+func decompress(h *Header, packed []byte, width, lines int) ([]byte, error) {
+	size := lines * h.lineSize(width)
+	if len(packed) == size {
+		// Data is stored uncompressed
+		return packed, nil
+	}
+
+	switch h.Compression {
+	case CompressionNone:
+		return nil, FormatError("invalid uncompressed block size")
+	case CompressionRLE:
+		raw, err := rleDecompress(packed, size)
+		if err != nil {
+			return nil, err
+		}
+		return deinterleave(unpredict(raw)), nil
+	case CompressionZIPS, CompressionZIP:
+		zr, err := zlib.NewReader(bytes.NewReader(packed))
+		if err != nil {
+			return nil, FormatError("invalid zip block")
+		}
+		defer zr.Close()
+
+		raw := make([]byte, size)
+		if _, err = io.ReadFull(zr, raw); err != nil {
+			return nil, FormatError("invalid zip block")
+		}
+		return deinterleave(unpredict(raw)), nil
+	case CompressionPIZ:
+		return pizDecompress(h.Channels, packed, width, lines)
+	case CompressionPXR24:
+		return pxr24Decompress(h.Channels, packed, width, lines)
+	case CompressionB44, CompressionB44A:
+		return b44Decompress(h.Channels, packed, width, lines)
+	}
+
+	return nil, UnsupportedError("compression")
+}
+
+//--------------------------------------//
+// Predictor & interleaving             //
+//--------------------------------------//
+
+// interleave splits data in two halves, even bytes first and odd bytes last.
+func interleave(data []byte) []byte {
+	t := make([]byte, len(data))
+	half := (len(data) + 1) / 2
+
+	for i := range data {
+		if i%2 == 0 {
+			t[i/2] = data[i]
+		} else {
+			t[half+i/2] = data[i]
+		}
+	}
+
+	return t
+}
+
+// deinterleave merges the two halves produced by interleave.
+func deinterleave(t []byte) []byte {
+	data := make([]byte, len(t))
+	half := (len(t) + 1) / 2
+
+	for i := range data {
+		if i%2 == 0 {
+			data[i] = t[i/2]
+		} else {
+			data[i] = t[half+i/2]
+		}
+	}
+
+	return data
+}
+
+// predict replaces each byte with its difference to the previous one (in place).
+func predict(t []byte) []byte {
+	if len(t) == 0 {
+		return t
+	}
+
+	p := t[0]
+	for i := 1; i < len(t); i++ {
+		d := t[i] - p + 128
+		p = t[i]
+		t[i] = d
+	}
+
+	return t
+}
+
+// unpredict reverts predict (in place).
+func unpredict(t []byte) []byte {
+	for i := 1; i < len(t); i++ {
+		t[i] = t[i-1] + t[i] - 128
+	}
+
+	return t
+}
+
+//--------------------------------------//
+// Run-length encoding                  //
+//--------------------------------------//
+
+const (
+	minRunLength = 3
+	maxRunLength = 127
+)
+
+func rleCompress(in []byte) []byte {
+	out := make([]byte, 0, len(in))
+	runs := 0
+	end := 1
+
+	for runs < len(in) {
+		for end < len(in) && in[runs] == in[end] && end-runs-1 < maxRunLength {
+			end++
+		}
+
+		if end-runs >= minRunLength {
+			// A run of the same value
+			out = append(out, byte(end-runs-1), in[runs])
+			runs = end
+		} else {
+			// A non-run, stopped at the beginning of the next run
+			for end < len(in) &&
+				(end+1 >= len(in) || in[end] != in[end+1] || end+2 >= len(in) || in[end+1] != in[end+2]) &&
+				end-runs < maxRunLength {
+				end++
+			}
+
+			out = append(out, byte(runs-end))
+			out = append(out, in[runs:end]...)
+			runs = end
+		}
+
+		end++
+	}
+
+	return out
+}
+
+func rleDecompress(in []byte, size int) ([]byte, error) {
+	out := make([]byte, 0, size)
+
+	for len(in) > 0 {
+		count := int(int8(in[0]))
+		in = in[1:]
+
+		if count < 0 {
+			// A non-run
+			count = -count
+			if count > len(in) || len(out)+count > size {
+				return nil, FormatError("invalid rle block")
+			}
+			out = append(out, in[:count]...)
+			in = in[count:]
+		} else {
+			// A run of the same value
+			if len(in) < 1 || len(out)+count+1 > size {
+				return nil, FormatError("invalid rle block")
+			}
+			for ; count >= 0; count-- {
+				out = append(out, in[0])
+			}
+			in = in[1:]
+		}
+	}
+
+	if len(out) != size {
+		return nil, FormatError("difference in size while reading RLE block")
+	}
+
+	return out, nil
+}
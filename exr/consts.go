@@ -0,0 +1,101 @@
+package exr
+
+const (
+	header = "\x76\x2f\x31\x01" // magic number 20000630
+
+	version = 2
+
+	// Version field flags
+	flagTiled     = 0x200
+	flagLongNames = 0x400
+	flagDeep      = 0x800
+	flagMultiPart = 0x1000
+)
+
+// A Compression is a compression method applied on pixel blocks.
+type Compression uint8
+
+const (
+	// CompressionNone for uncompressed data
+	CompressionNone Compression = iota
+	// CompressionRLE for run-length encoding
+	CompressionRLE
+	// CompressionZIPS for zlib compression, one scanline at a time
+	CompressionZIPS
+	// CompressionZIP for zlib compression, in blocks of 16 scanlines
+	CompressionZIP
+	// CompressionPIZ for wavelet and Huffman compression
+	CompressionPIZ
+	// CompressionPXR24 for lossy 24-bit float compression
+	CompressionPXR24
+	// CompressionB44 for lossy 4-by-4 pixel block compression
+	CompressionB44
+	// CompressionB44A for lossy 4-by-4 pixel block compression with flat fields
+	CompressionB44A
+)
+
+// linesPerBlock returns the number of scanlines stored in one chunk.
+func (c Compression) linesPerBlock() int {
+	switch c {
+	case CompressionZIP, CompressionPXR24:
+		return 16
+	case CompressionPIZ, CompressionB44, CompressionB44A:
+		return 32
+	default:
+		return 1
+	}
+}
+
+// A PixelType is the data type of a channel.
+type PixelType int32
+
+const (
+	// PixelTypeUint for 32-bit unsigned integers
+	PixelTypeUint PixelType = iota
+	// PixelTypeHalf for 16-bit floating points
+	PixelTypeHalf
+	// PixelTypeFloat for 32-bit floating points
+	PixelTypeFloat
+)
+
+// size returns the number of bytes of one value.
+func (t PixelType) size() int {
+	if t == PixelTypeHalf {
+		return 2
+	}
+	return 4
+}
+
+// A LineOrder is the order in which scanline blocks are stored.
+type LineOrder uint8
+
+const (
+	// LineOrderIncreasingY stores the top scanline first
+	LineOrderIncreasingY LineOrder = iota
+	// LineOrderDecreasingY stores the bottom scanline first
+	LineOrderDecreasingY
+	// LineOrderRandomY stores scanlines in any order
+	LineOrderRandomY
+)
+
+// A LevelMode is the resolution levels layout of a tiled image.
+type LevelMode uint8
+
+const (
+	// LevelModeOne for a single full resolution level
+	LevelModeOne LevelMode = iota
+	// LevelModeMipmap for levels reduced in both dimensions
+	LevelModeMipmap
+	// LevelModeRipmap for levels reduced in each dimension independently
+	LevelModeRipmap
+)
+
+// A RoundingMode is the rounding direction of the levels size.
+type RoundingMode uint8
+
+const (
+	// RoundingModeDown rounds levels size down
+	RoundingModeDown RoundingMode = iota
+	// RoundingModeUp rounds levels size up
+	RoundingModeUp
+)
@@ -0,0 +1,299 @@
+package exr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"io"
+	"math"
+	"sort"
+)
+
+// A Channel describes one channel stored in the image.
+type Channel struct {
+	Name      string
+	PixelType PixelType
+	PLinear   bool
+	XSampling int
+	YSampling int
+}
+
+// A TileDescription describes the tiles layout of a tiled image.
+type TileDescription struct {
+	XSize        int
+	YSize        int
+	LevelMode    LevelMode
+	RoundingMode RoundingMode
+}
+
+// A Header handles all image properties.
+type Header struct {
+	Channels           []Channel
+	Compression        Compression
+	DataWindow         image.Rectangle
+	DisplayWindow      image.Rectangle
+	LineOrder          LineOrder
+	PixelAspectRatio   float32
+	ScreenWindowCenter [2]float32
+	ScreenWindowWidth  float32
+	// Tiles is only defined for tiled images.
+	Tiles *TileDescription
+}
+
+// maxAttributeSize avoids huge allocations on corrupted headers.
+const maxAttributeSize = 1 << 24
+
+//--------------------------------------//
+// Attributes parser                    //
+//--------------------------------------//
+
+func readHeader(r io.Reader) (*Header, error) {
+	h := &Header{
+		PixelAspectRatio:  1,
+		ScreenWindowWidth: 1,
+	}
+	required := map[string]bool{
+		"channels":    false,
+		"compression": false,
+		"dataWindow":  false,
+	}
+
+	for {
+		name, err := readUntil(r, 0)
+		if err != nil {
+			return nil, err
+		}
+		if name == "" {
+			// End of header
+			break
+		}
+
+		typ, err := readUntil(r, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		var size int32
+		if err = binary.Read(r, binary.LittleEndian, &size); err != nil {
+			return nil, err
+		}
+		if size < 0 || size > maxAttributeSize {
+			return nil, FormatError("invalid attribute size")
+		}
+
+		if _, ok := required[name]; ok {
+			required[name] = true
+		}
+
+		if err = h.parseAttribute(r, name, typ, int(size)); err != nil {
+			return nil, err
+		}
+	}
+
+	for name, found := range required {
+		if !found {
+			return nil, FormatError("missing required attribute " + name)
+		}
+	}
+
+	return h, nil
+}
+
+func (h *Header) parseAttribute(r io.Reader, name, typ string, size int) error {
+	value := make([]byte, size)
+	if _, err := io.ReadFull(r, value); err != nil {
+		return err
+	}
+
+	var err error
+	switch {
+	case name == "channels" && typ == "chlist":
+		h.Channels, err = parseChannels(value)
+	case name == "compression" && typ == "compression":
+		if len(value) != 1 {
+			return FormatError("invalid compression attribute")
+		}
+		h.Compression = Compression(value[0])
+	case name == "dataWindow" && typ == "box2i":
+		h.DataWindow, err = parseBox2i(value)
+	case name == "displayWindow" && typ == "box2i":
+		h.DisplayWindow, err = parseBox2i(value)
+	case name == "lineOrder" && typ == "lineOrder":
+		if len(value) != 1 {
+			return FormatError("invalid lineOrder attribute")
+		}
+		h.LineOrder = LineOrder(value[0])
+	case name == "pixelAspectRatio" && typ == "float":
+		h.PixelAspectRatio, err = parseFloat(value)
+	case name == "screenWindowCenter" && typ == "v2f":
+		if len(value) != 8 {
+			return FormatError("invalid screenWindowCenter attribute")
+		}
+		h.ScreenWindowCenter[0] = math.Float32frombits(binary.LittleEndian.Uint32(value))
+		h.ScreenWindowCenter[1] = math.Float32frombits(binary.LittleEndian.Uint32(value[4:]))
+	case name == "screenWindowWidth" && typ == "float":
+		h.ScreenWindowWidth, err = parseFloat(value)
+	case name == "tiles" && typ == "tiledesc":
+		if len(value) != 9 {
+			return FormatError("invalid tiles attribute")
+		}
+		h.Tiles = &TileDescription{
+			XSize:        int(binary.LittleEndian.Uint32(value[0:])),
+			YSize:        int(binary.LittleEndian.Uint32(value[4:])),
+			LevelMode:    LevelMode(value[8] & 0x0f),
+			RoundingMode: RoundingMode(value[8] >> 4),
+		}
+	}
+	// Other attributes are ignored
+
+	return err
+}
+
+// lineSize returns the number of bytes of one raw scanline of the given width.
+func (h *Header) lineSize(width int) int {
+	var size int
+	for _, c := range h.Channels {
+		size += width * c.PixelType.size()
+	}
+	return size
+}
+
+func parseChannels(value []byte) ([]Channel, error) {
+	var channels []Channel
+	r := bytes.NewReader(value)
+
+	for {
+		name, err := readUntil(r, 0)
+		if err != nil {
+			return nil, FormatError("invalid channel list")
+		}
+		if name == "" {
+			break
+		}
+
+		var raw struct {
+			PixelType PixelType
+			PLinear   uint8
+			Reserved  [3]uint8
+			XSampling int32
+			YSampling int32
+		}
+		if err = binary.Read(r, binary.LittleEndian, &raw); err != nil {
+			return nil, FormatError("invalid channel list")
+		}
+		if raw.PixelType < PixelTypeUint || raw.PixelType > PixelTypeFloat {
+			return nil, FormatError("invalid channel pixel type")
+		}
+
+		channels = append(channels, Channel{
+			Name:      name,
+			PixelType: raw.PixelType,
+			PLinear:   raw.PLinear != 0,
+			XSampling: int(raw.XSampling),
+			YSampling: int(raw.YSampling),
+		})
+	}
+
+	return channels, nil
+}
+
+func parseBox2i(value []byte) (image.Rectangle, error) {
+	if len(value) != 16 {
+		return image.Rectangle{}, FormatError("invalid box2i attribute")
+	}
+
+	xMin := int32(binary.LittleEndian.Uint32(value[0:]))
+	yMin := int32(binary.LittleEndian.Uint32(value[4:]))
+	xMax := int32(binary.LittleEndian.Uint32(value[8:]))
+	yMax := int32(binary.LittleEndian.Uint32(value[12:]))
+
+	// Box2i boundaries are inclusive
+	return image.Rect(int(xMin), int(yMin), int(xMax)+1, int(yMax)+1), nil
+}
+
+func parseFloat(value []byte) (float32, error) {
+	if len(value) != 4 {
+		return 0, FormatError("invalid float attribute")
+	}
+	return math.Float32frombits(binary.LittleEndian.Uint32(value)), nil
+}
+
+//--------------------------------------//
+// Attributes writer                    //
+//--------------------------------------//
+
+func (h *Header) writeTo(w io.Writer) error {
+	attrs := map[string]struct {
+		typ   string
+		value []byte
+	}{
+		"channels":           {"chlist", h.marshalChannels()},
+		"compression":        {"compression", []byte{byte(h.Compression)}},
+		"dataWindow":         {"box2i", marshalBox2i(h.DataWindow)},
+		"displayWindow":      {"box2i", marshalBox2i(h.DisplayWindow)},
+		"lineOrder":          {"lineOrder", []byte{byte(h.LineOrder)}},
+		"pixelAspectRatio":   {"float", marshalFloats(h.PixelAspectRatio)},
+		"screenWindowCenter": {"v2f", marshalFloats(h.ScreenWindowCenter[0], h.ScreenWindowCenter[1])},
+		"screenWindowWidth":  {"float", marshalFloats(h.ScreenWindowWidth)},
+	}
+
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := &bytes.Buffer{}
+	for _, name := range names {
+		attr := attrs[name]
+
+		buf.WriteString(name)
+		buf.WriteByte(0)
+		buf.WriteString(attr.typ)
+		buf.WriteByte(0)
+		binary.Write(buf, binary.LittleEndian, int32(len(attr.value)))
+		buf.Write(attr.value)
+	}
+	buf.WriteByte(0) // End of header
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func (h *Header) marshalChannels() []byte {
+	buf := &bytes.Buffer{}
+
+	for _, c := range h.Channels {
+		buf.WriteString(c.Name)
+		buf.WriteByte(0)
+		binary.Write(buf, binary.LittleEndian, int32(c.PixelType))
+		if c.PLinear {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+		buf.Write([]byte{0, 0, 0}) // Reserved
+		binary.Write(buf, binary.LittleEndian, int32(c.XSampling))
+		binary.Write(buf, binary.LittleEndian, int32(c.YSampling))
+	}
+	buf.WriteByte(0) // End of list
+
+	return buf.Bytes()
+}
+
+func marshalBox2i(r image.Rectangle) []byte {
+	value := make([]byte, 16)
+	binary.LittleEndian.PutUint32(value[0:], uint32(int32(r.Min.X)))
+	binary.LittleEndian.PutUint32(value[4:], uint32(int32(r.Min.Y)))
+	binary.LittleEndian.PutUint32(value[8:], uint32(int32(r.Max.X-1)))
+	binary.LittleEndian.PutUint32(value[12:], uint32(int32(r.Max.Y-1)))
+	return value
+}
+
+func marshalFloats(fs ...float32) []byte {
+	value := make([]byte, 4*len(fs))
+	for i, f := range fs {
+		binary.LittleEndian.PutUint32(value[4*i:], math.Float32bits(f))
+	}
+	return value
+}
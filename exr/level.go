@@ -0,0 +1,75 @@
+package exr
+
+import (
+	"image"
+
+	"github.com/Xyzyx101/hdr"
+)
+
+// A Level is one resolution level of a multi-resolution image.
+type Level struct {
+	// X and Y are the level numbers (X equals Y for mipmaps).
+	// The level (0, 0) is the full resolution image.
+	X, Y  int
+	Image hdr.Image
+}
+
+// numLevels returns the number of levels in x and y.
+func (t *TileDescription) numLevels(width, height int) (nx, ny int) {
+	switch t.LevelMode {
+	case LevelModeMipmap:
+		max := width
+		if height > max {
+			max = height
+		}
+		nx = roundLog2(max, t.RoundingMode) + 1
+		ny = nx
+	case LevelModeRipmap:
+		nx = roundLog2(width, t.RoundingMode) + 1
+		ny = roundLog2(height, t.RoundingMode) + 1
+	default:
+		nx, ny = 1, 1
+	}
+
+	return
+}
+
+// levelSize returns the size of the level l of a size length.
+func (t *TileDescription) levelSize(size, l int) int {
+	if t.RoundingMode == RoundingModeUp {
+		size += 1<<uint(l) - 1
+	}
+	size >>= uint(l)
+
+	if size < 1 {
+		return 1
+	}
+	return size
+}
+
+// levelRect returns the bounds of the level (lx, ly).
+func (t *TileDescription) levelRect(width, height, lx, ly int) image.Rectangle {
+	return image.Rect(0, 0, t.levelSize(width, lx), t.levelSize(height, ly))
+}
+
+// numTiles returns the number of tiles in x and y of the level bounds r.
+func (t *TileDescription) numTiles(r image.Rectangle) (nx, ny int) {
+	return (r.Dx() + t.XSize - 1) / t.XSize, (r.Dy() + t.YSize - 1) / t.YSize
+}
+
+func roundLog2(x int, mode RoundingMode) int {
+	y := 0
+
+	if mode == RoundingModeUp {
+		for 1<<uint(y) < x {
+			y++
+		}
+		return y
+	}
+
+	for x > 1 {
+		x >>= 1
+		y++
+	}
+	return y
+}
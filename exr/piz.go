@@ -0,0 +1,506 @@
+package exr
+
+import (
+	"encoding/binary"
+)
+
+// PIZ compression: a lookup table compacts the 16-bit values range,
+// then a 2D Haar wavelet transform is applied on each channel and
+// the result is Huffman encoded.
+
+const (
+	ushortRange = 1 << 16
+	bitmapSize  = ushortRange >> 3
+)
+
+func pizDecompress(channels []Channel, packed []byte, width, lines int) ([]byte, error) {
+	if len(packed) < 4 {
+		return nil, FormatError("invalid piz block")
+	}
+
+	// Bitmap of the used values
+	minNonZero := int(binary.LittleEndian.Uint16(packed[0:]))
+	maxNonZero := int(binary.LittleEndian.Uint16(packed[2:]))
+	packed = packed[4:]
+	if maxNonZero >= bitmapSize {
+		return nil, FormatError("invalid piz bitmap size")
+	}
+
+	bitmap := make([]byte, bitmapSize)
+	if minNonZero <= maxNonZero {
+		n := maxNonZero - minNonZero + 1
+		if len(packed) < n {
+			return nil, FormatError("invalid piz bitmap")
+		}
+		copy(bitmap[minNonZero:], packed[:n])
+		packed = packed[n:]
+	}
+
+	lut, maxValue := reverseLutFromBitmap(bitmap)
+
+	// Huffman encoded wavelets
+	if len(packed) < 4 {
+		return nil, FormatError("invalid piz block")
+	}
+	length := int(int32(binary.LittleEndian.Uint32(packed)))
+	packed = packed[4:]
+	if length < 0 || length > len(packed) {
+		return nil, FormatError("invalid piz data length")
+	}
+
+	var nbOfValues int
+	for _, c := range channels {
+		nbOfValues += width * lines * c.PixelType.size() / 2
+	}
+
+	values := make([]uint16, nbOfValues)
+	if err := hufUncompress(packed[:length], values); err != nil {
+		return nil, err
+	}
+
+	// Wavelet decoding, channels are stored one after another
+	offset := 0
+	for _, c := range channels {
+		n := c.PixelType.size() / 2 // Number of uint16 per pixel
+		for j := 0; j < n; j++ {
+			wav2Decode(values[offset+j:], width, n, lines, width*n, maxValue)
+		}
+		offset += width * lines * n
+	}
+
+	for i, v := range values {
+		values[i] = lut[v]
+	}
+
+	// Restore the scanlines interleaving
+	raw := make([]byte, 0, 2*nbOfValues)
+	offsets := make([]int, len(channels))
+	offset = 0
+	for i, c := range channels {
+		offsets[i] = offset
+		offset += width * lines * c.PixelType.size() / 2
+	}
+	for y := 0; y < lines; y++ {
+		for i, c := range channels {
+			n := width * c.PixelType.size() / 2
+			for _, v := range values[offsets[i] : offsets[i]+n] {
+				raw = append(raw, byte(v), byte(v>>8))
+			}
+			offsets[i] += n
+		}
+	}
+
+	return raw, nil
+}
+
+func reverseLutFromBitmap(bitmap []byte) (lut []uint16, maxValue uint16) {
+	lut = make([]uint16, ushortRange)
+
+	k := 0
+	for i := 0; i < ushortRange; i++ {
+		if i == 0 || bitmap[i>>3]&(1<<uint(i&7)) != 0 {
+			lut[k] = uint16(i)
+			k++
+		}
+	}
+
+	return lut, uint16(k - 1)
+}
+
+//--------------------------------------//
+// Wavelet                              //
+//--------------------------------------//
+
+const (
+	waveletBits    = 16
+	waveletOffset  = 1 << (waveletBits - 1)
+	waveletModMask = 1<<waveletBits - 1
+)
+
+// wdec14 is the 14-bit inverse Haar transform.
+func wdec14(l, h uint16) (a, b uint16) {
+	ls := int(int16(l))
+	hs := int(int16(h))
+
+	ai := ls + (hs & 1) + (hs >> 1)
+	return uint16(int16(ai)), uint16(int16(ai - hs))
+}
+
+// wdec16 is the 16-bit inverse Haar transform with modulo arithmetic.
+func wdec16(l, h uint16) (a, b uint16) {
+	m := int(l)
+	d := int(h)
+
+	bb := (m - (d >> 1)) & waveletModMask
+	aa := (d + bb - waveletOffset) & waveletModMask
+	return uint16(aa), uint16(bb)
+}
+
+// wav2Decode applies the inverse 2D wavelet transform on in (in place).
+// nx and ny are the dimensions, ox and oy the offsets between two adjacent values.
+func wav2Decode(in []uint16, nx, ox, ny, oy int, mx uint16) {
+	wdec := wdec16
+	if mx < 1<<14 {
+		wdec = wdec14
+	}
+
+	n := ny
+	if nx < ny {
+		n = nx
+	}
+
+	// Search max level
+	p := 1
+	for p <= n {
+		p <<= 1
+	}
+	p >>= 1
+	p2 := p
+	p >>= 1
+
+	// Hierarchical loop on smaller dimension n
+	for p >= 1 {
+		py := 0
+		ey := oy * (ny - p2)
+		oy1 := oy * p
+		oy2 := oy * p2
+		ox1 := ox * p
+		ox2 := ox * p2
+
+		// Y loop
+		for ; py <= ey; py += oy2 {
+			px := py
+			ex := py + ox*(nx-p2)
+
+			// X loop
+			for ; px <= ex; px += ox2 {
+				p01 := px + ox1
+				p10 := px + oy1
+				p11 := p10 + ox1
+
+				// 2D wavelet decoding
+				i00, i10 := wdec(in[px], in[p10])
+				i01, i11 := wdec(in[p01], in[p11])
+				in[px], in[p01] = wdec(i00, i01)
+				in[p10], in[p11] = wdec(i10, i11)
+			}
+
+			// Decode (1D) odd column
+			if nx&p != 0 {
+				p10 := px + oy1
+				in[px], in[p10] = wdec(in[px], in[p10])
+			}
+		}
+
+		// Decode (1D) odd line
+		if ny&p != 0 {
+			px := py
+			ex := py + ox*(nx-p2)
+
+			for ; px <= ex; px += ox2 {
+				p01 := px + ox1
+				in[px], in[p01] = wdec(in[px], in[p01])
+			}
+		}
+
+		// Next level
+		p2 = p
+		p >>= 1
+	}
+}
+
+//--------------------------------------//
+// Huffman                              //
+//--------------------------------------//
+
+const (
+	hufEncBits = 16 // literal (value) bit length
+	hufDecBits = 14 // decoding bit size (>= 8)
+
+	hufEncSize = 1<<hufEncBits + 1 // encoding table size
+	hufDecSize = 1 << hufDecBits   // decoding table size
+	hufDecMask = hufDecSize - 1
+
+	shortZeroCodeRun = 59
+	longZeroCodeRun  = 63
+	shortestLongRun  = 2 + longZeroCodeRun - shortZeroCodeRun
+)
+
+// A hufDec is an entry of the decoding table.
+type hufDec struct {
+	len int      // short code length
+	lit int      // short code value or number of long codes
+	p   []uint32 // long codes values
+}
+
+// bitReader reads bits in most significant bit first order.
+type bitReader struct {
+	in []byte
+	c  uint64 // bits buffer
+	lc uint   // number of bits in the buffer
+}
+
+func (br *bitReader) getChar() {
+	br.c = br.c<<8 | uint64(br.in[0])
+	br.in = br.in[1:]
+	br.lc += 8
+}
+
+func (br *bitReader) getBits(n uint) (uint64, error) {
+	for br.lc < n {
+		if len(br.in) == 0 {
+			return 0, FormatError("unexpected end of huffman data")
+		}
+		br.getChar()
+	}
+	br.lc -= n
+	return (br.c >> br.lc) & (1<<n - 1), nil
+}
+
+func hufUncompress(compressed []byte, raw []uint16) error {
+	if len(compressed) == 0 {
+		if len(raw) != 0 {
+			return FormatError("not enough huffman data")
+		}
+		return nil
+	}
+	if len(compressed) < 20 {
+		return FormatError("invalid huffman header")
+	}
+
+	im := int(binary.LittleEndian.Uint32(compressed[0:]))
+	iM := int(binary.LittleEndian.Uint32(compressed[4:]))
+	nBits := int(binary.LittleEndian.Uint32(compressed[12:]))
+	compressed = compressed[20:]
+
+	if im < 0 || im >= hufEncSize || iM < 0 || iM >= hufEncSize || im > iM {
+		return FormatError("invalid huffman table size")
+	}
+
+	hcode, rest, err := hufUnpackEncTable(compressed, im, iM)
+	if err != nil {
+		return err
+	}
+	if nBits < 0 || nBits > 8*len(rest) {
+		return FormatError("invalid huffman bits number")
+	}
+
+	hdec, err := hufBuildDecTable(hcode, im, iM)
+	if err != nil {
+		return err
+	}
+
+	return hufDecode(hcode, hdec, rest, nBits, iM, raw)
+}
+
+// hufUnpackEncTable reads the code lengths of values [im, iM] and builds the canonical codes.
+func hufUnpackEncTable(in []byte, im, iM int) (hcode []uint64, rest []byte, err error) {
+	hcode = make([]uint64, hufEncSize)
+	br := &bitReader{in: in}
+
+	for ; im <= iM; im++ {
+		var l uint64
+		l, err = br.getBits(6) // code length
+		if err != nil {
+			return
+		}
+		hcode[im] = l
+
+		if l == longZeroCodeRun {
+			var zerun uint64
+			zerun, err = br.getBits(8)
+			if err != nil {
+				return
+			}
+			zerun += shortestLongRun
+
+			if im+int(zerun) > iM+1 {
+				err = FormatError("huffman table too long")
+				return
+			}
+			for ; zerun > 0; zerun-- {
+				hcode[im] = 0
+				im++
+			}
+			im--
+		} else if l >= shortZeroCodeRun {
+			zerun := int(l) - shortZeroCodeRun + 2
+
+			if im+zerun > iM+1 {
+				err = FormatError("huffman table too long")
+				return
+			}
+			for ; zerun > 0; zerun-- {
+				hcode[im] = 0
+				im++
+			}
+			im--
+		}
+	}
+
+	hufCanonicalCodeTable(hcode)
+	return hcode, br.in, nil
+}
+
+// hufCanonicalCodeTable builds a canonical Huffman code table from code lengths.
+// Each entry becomes code<<6 | length.
+func hufCanonicalCodeTable(hcode []uint64) {
+	var n [59]uint64
+	for _, l := range hcode {
+		n[l]++
+	}
+
+	var c uint64
+	for i := 58; i > 0; i-- {
+		nc := (c + n[i]) >> 1
+		n[i] = c
+		c = nc
+	}
+
+	for i, l := range hcode {
+		if l > 0 {
+			hcode[i] = l | n[l]<<6
+			n[l]++
+		}
+	}
+}
+
+func hufLength(code uint64) uint { return uint(code & 63) }
+func hufCode(code uint64) uint64 { return code >> 6 }
+
+func hufBuildDecTable(hcode []uint64, im, iM int) ([]hufDec, error) {
+	hdec := make([]hufDec, hufDecSize)
+
+	for ; im <= iM; im++ {
+		c := hufCode(hcode[im])
+		l := hufLength(hcode[im])
+
+		if c>>l != 0 {
+			return nil, FormatError("invalid huffman table entry")
+		}
+
+		if l > hufDecBits {
+			// Long code: add a secondary entry
+			pl := &hdec[c>>(l-hufDecBits)]
+			if pl.len != 0 {
+				return nil, FormatError("invalid huffman table entry")
+			}
+			pl.lit++
+			pl.p = append(pl.p, uint32(im))
+		} else if l != 0 {
+			// Short code: init all primary entries
+			i := c << (hufDecBits - l)
+			for n := uint64(1) << (hufDecBits - l); n > 0; n-- {
+				pl := &hdec[i]
+				if pl.len != 0 || pl.p != nil {
+					return nil, FormatError("invalid huffman table entry")
+				}
+				pl.len = int(l)
+				pl.lit = im
+				i++
+			}
+		}
+	}
+
+	return hdec, nil
+}
+
+func hufDecode(hcode []uint64, hdec []hufDec, in []byte, nBits, rlc int, out []uint16) error {
+	br := &bitReader{in: in[:(nBits+7)/8]}
+	o := 0
+
+	getCode := func(po int) error {
+		if po == rlc {
+			// Run-length of the previous value
+			if br.lc < 8 {
+				if len(br.in) == 0 {
+					return FormatError("unexpected end of huffman data")
+				}
+				br.getChar()
+			}
+			br.lc -= 8
+			cs := int(byte(br.c >> br.lc))
+
+			if o+cs > len(out) {
+				return FormatError("too much huffman data")
+			}
+			if o < 1 {
+				return FormatError("not enough huffman data")
+			}
+			s := out[o-1]
+			for ; cs > 0; cs-- {
+				out[o] = s
+				o++
+			}
+		} else if o < len(out) {
+			out[o] = uint16(po)
+			o++
+		} else {
+			return FormatError("too much huffman data")
+		}
+		return nil
+	}
+
+	for len(br.in) > 0 {
+		br.getChar()
+
+		for br.lc >= hufDecBits {
+			pl := hdec[(br.c>>(br.lc-hufDecBits))&hufDecMask]
+
+			if pl.len != 0 {
+				br.lc -= uint(pl.len)
+				if err := getCode(pl.lit); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if pl.p == nil {
+				return FormatError("invalid huffman code")
+			}
+
+			// Search long code
+			j := 0
+			for ; j < pl.lit; j++ {
+				l := hufLength(hcode[pl.p[j]])
+				for br.lc < l && len(br.in) > 0 {
+					br.getChar()
+				}
+				if br.lc >= l && hufCode(hcode[pl.p[j]]) == (br.c>>(br.lc-l))&(1<<l-1) {
+					br.lc -= l
+					if err := getCode(int(pl.p[j])); err != nil {
+						return err
+					}
+					break
+				}
+			}
+			if j == pl.lit {
+				return FormatError("invalid huffman code")
+			}
+		}
+	}
+
+	// Get remaining (short) codes
+	i := uint((8 - nBits) & 7)
+	if br.lc < i {
+		return FormatError("invalid huffman bits number")
+	}
+	br.c >>= i
+	br.lc -= i
+	for br.lc > 0 {
+		pl := hdec[(br.c<<(hufDecBits-br.lc))&hufDecMask]
+		if pl.len == 0 || uint(pl.len) > br.lc {
+			return FormatError("invalid huffman code")
+		}
+		br.lc -= uint(pl.len)
+		if err := getCode(pl.lit); err != nil {
+			return err
+		}
+	}
+
+	if o != len(out) {
+		return FormatError("not enough huffman data")
+	}
+
+	return nil
+}
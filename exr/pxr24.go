@@ -0,0 +1,70 @@
+package exr
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io/ioutil"
+)
+
+// PXR24 compression: FLOAT values are rounded to 24 bits, each value is
+// replaced by its difference to the previous one, the bytes are split in
+// planes (most significant first) and zlib compressed.
+
+func pxr24Decompress(channels []Channel, packed []byte, width, lines int) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(packed))
+	if err != nil {
+		return nil, FormatError("invalid pxr24 block")
+	}
+	defer zr.Close()
+
+	tmp, err := ioutil.ReadAll(zr)
+	if err != nil {
+		return nil, FormatError("invalid pxr24 block")
+	}
+
+	var size int
+	for _, c := range channels {
+		size += width * c.PixelType.size()
+	}
+	raw := make([]byte, 0, size*lines)
+
+	for y := 0; y < lines; y++ {
+		for _, c := range channels {
+			planes := 4
+			switch c.PixelType {
+			case PixelTypeHalf:
+				planes = 2
+			case PixelTypeFloat:
+				planes = 3
+			}
+
+			if len(tmp) < planes*width {
+				return nil, FormatError("not enough pxr24 data")
+			}
+
+			var pixel uint32
+			for x := 0; x < width; x++ {
+				var diff uint32
+				for p := 0; p < planes; p++ {
+					diff = diff<<8 | uint32(tmp[p*width+x])
+				}
+
+				switch c.PixelType {
+				case PixelTypeUint:
+					pixel += diff
+					raw = append(raw, byte(pixel), byte(pixel>>8), byte(pixel>>16), byte(pixel>>24))
+				case PixelTypeHalf:
+					pixel += diff
+					raw = append(raw, byte(pixel), byte(pixel>>8))
+				case PixelTypeFloat:
+					pixel += diff << 8
+					raw = append(raw, byte(pixel), byte(pixel>>8), byte(pixel>>16), byte(pixel>>24))
+				}
+			}
+
+			tmp = tmp[planes*width:]
+		}
+	}
+
+	return raw, nil
+}
@@ -0,0 +1,422 @@
+package exr
+
+// Resources:
+// http://www.openexr.com/documentation/openexrfilelayout.pdf
+// http://www.openexr.com/documentation/TechnicalIntroduction.pdf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"image"
+	"io"
+	"io/ioutil"
+	"math"
+
+	"github.com/Xyzyx101/hdr"
+	"github.com/Xyzyx101/hdr/format"
+	"github.com/Xyzyx101/hdr/hdrcolor"
+)
+
+// maxSize avoids huge allocations on corrupted headers.
+const maxSize = 1 << 30
+
+type decoder struct {
+	r      io.Reader
+	h      *Header
+	config image.Config
+	flags  uint32
+}
+
+func newDecoder(r io.Reader) (*decoder, error) {
+	d := &decoder{
+		r: bufio.NewReader(r),
+	}
+
+	return d, d.parseHeader()
+}
+
+//--------------------------------------//
+// Header parser                        //
+//--------------------------------------//
+
+func (d *decoder) parseHeader() error {
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(d.r, magic); err != nil {
+		return err
+	}
+	if string(magic) != header {
+		return FormatError("format not compatible")
+	}
+
+	var v uint32
+	if err := binary.Read(d.r, binary.LittleEndian, &v); err != nil {
+		return err
+	}
+	if v&0xff != version {
+		return UnsupportedError("version")
+	}
+	d.flags = v &^ 0xff
+
+	if d.flags&flagDeep != 0 {
+		return UnsupportedError("deep data")
+	}
+	if d.flags&flagMultiPart != 0 {
+		return UnsupportedError("multi-part file")
+	}
+
+	var err error
+	d.h, err = readHeader(d.r)
+	if err != nil {
+		return err
+	}
+
+	return d.validate()
+}
+
+func (d *decoder) validate() error {
+	dw := d.h.DataWindow
+	if dw.Empty() {
+		return FormatError("empty data window")
+	}
+	if dw.Dx() > maxSize/dw.Dy() {
+		return FormatError("image too large")
+	}
+
+	if len(d.h.Channels) == 0 {
+		return FormatError("missing channels")
+	}
+	for _, c := range d.h.Channels {
+		if c.XSampling != 1 || c.YSampling != 1 {
+			return UnsupportedError("channel sub-sampling")
+		}
+	}
+
+	if d.h.Compression > CompressionB44A {
+		return UnsupportedError("compression")
+	}
+
+	if d.tiled() {
+		t := d.h.Tiles
+		if t == nil {
+			return FormatError("missing required attribute tiles")
+		}
+		if t.XSize <= 0 || t.YSize <= 0 || t.XSize > maxSize/t.YSize {
+			return FormatError("invalid tile size")
+		}
+		if t.LevelMode > LevelModeRipmap {
+			return UnsupportedError("level mode")
+		}
+		if t.RoundingMode > RoundingModeUp {
+			return UnsupportedError("rounding mode")
+		}
+	}
+
+	d.config.ColorModel = hdrcolor.RGBModel
+	if d.alpha() {
+		d.config.ColorModel = hdrcolor.RGBAModel
+	}
+	d.config.Width = dw.Dx()
+	d.config.Height = dw.Dy()
+
+	return nil
+}
+
+func (d *decoder) tiled() bool {
+	return d.flags&flagTiled != 0
+}
+
+//--------------------------------------//
+// Pixels parser                        //
+//--------------------------------------//
+
+// decodeBlock sets the pixels of the area rect with the raw block data.
+func (d *decoder) decodeBlock(dst image.Image, rect image.Rectangle, raw []byte) {
+	w := rect.Dx()
+	r := make([]float64, w)
+	g := make([]float64, w)
+	b := make([]float64, w)
+	a := make([]float64, w)
+
+	offset := 0
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for _, c := range d.h.Channels {
+			var line []float64
+			switch c.Name {
+			case "R":
+				line = r
+			case "G":
+				line = g
+			case "B":
+				line = b
+			case "Y":
+				line = r
+			case "A":
+				line = a
+			}
+
+			size := c.PixelType.size()
+			if line != nil {
+				for x := 0; x < w; x++ {
+					line[x] = readValue(c.PixelType, raw[offset+x*size:])
+				}
+				if c.Name == "Y" {
+					// Luminance only image
+					copy(g, r)
+					copy(b, r)
+				}
+			}
+			offset += w * size
+		}
+
+		for x := 0; x < w; x++ {
+			switch img := dst.(type) {
+			case *hdr.RGB:
+				img.SetRGB(rect.Min.X+x, y, hdrcolor.RGB{R: r[x], G: g[x], B: b[x]})
+			case *hdr.RGB64:
+				img.SetRGB(rect.Min.X+x, y, hdrcolor.RGB{R: r[x], G: g[x], B: b[x]})
+			case *hdr.RGBA:
+				img.SetRGBA(rect.Min.X+x, y, hdrcolor.RGBA{R: r[x], G: g[x], B: b[x], A: a[x]})
+			case *hdr.RGBA64:
+				img.SetRGBA(rect.Min.X+x, y, hdrcolor.RGBA{R: r[x], G: g[x], B: b[x], A: a[x]})
+			}
+		}
+	}
+}
+
+func readValue(t PixelType, b []byte) float64 {
+	switch t {
+	case PixelTypeHalf:
+		return float64(format.HalfToFloat(binary.LittleEndian.Uint16(b)))
+	case PixelTypeFloat:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	default:
+		return float64(binary.LittleEndian.Uint32(b))
+	}
+}
+
+// readBlock reads and decompresses the data of the given block area.
+func (d *decoder) readBlock(rect image.Rectangle, size int32) ([]byte, error) {
+	if size < 0 || int(size) > rect.Dy()*d.h.lineSize(rect.Dx()) {
+		return nil, FormatError("invalid chunk size")
+	}
+
+	packed := make([]byte, size)
+	if _, err := io.ReadFull(d.r, packed); err != nil {
+		return nil, err
+	}
+
+	return decompress(d.h, packed, rect.Dx(), rect.Dy())
+}
+
+func (d *decoder) readChunk(img image.Image) error {
+	var chunk struct {
+		Y    int32
+		Size int32
+	}
+	if err := binary.Read(d.r, binary.LittleEndian, &chunk); err != nil {
+		return err
+	}
+
+	dw := d.h.DataWindow
+	lpb := d.h.Compression.linesPerBlock()
+	y := int(chunk.Y)
+	if y < dw.Min.Y || y >= dw.Max.Y || (y-dw.Min.Y)%lpb != 0 {
+		return FormatError("invalid chunk coordinate")
+	}
+
+	lines := lpb
+	if dw.Max.Y-y < lines {
+		lines = dw.Max.Y - y
+	}
+	rect := image.Rect(0, y-dw.Min.Y, d.config.Width, y-dw.Min.Y+lines)
+
+	raw, err := d.readBlock(rect, chunk.Size)
+	if err != nil {
+		return err
+	}
+
+	d.decodeBlock(img, rect, raw)
+	return nil
+}
+
+// readTile reads one tile chunk and decodes it in the matching level image.
+// Tiles of levels missing in the levels map are skipped.
+func (d *decoder) readTile(levels map[image.Point]hdr.Image) error {
+	var chunk struct {
+		TileX, TileY   int32
+		LevelX, LevelY int32
+		Size           int32
+	}
+	if err := binary.Read(d.r, binary.LittleEndian, &chunk); err != nil {
+		return err
+	}
+
+	t := d.h.Tiles
+	lx, ly := int(chunk.LevelX), int(chunk.LevelY)
+	nlx, nly := t.numLevels(d.config.Width, d.config.Height)
+	if lx < 0 || lx >= nlx || ly < 0 || ly >= nly || (t.LevelMode != LevelModeRipmap && lx != ly) {
+		return FormatError("invalid tile level")
+	}
+
+	lr := t.levelRect(d.config.Width, d.config.Height, lx, ly)
+	tx, ty := int(chunk.TileX), int(chunk.TileY)
+	ntx, nty := t.numTiles(lr)
+	if tx < 0 || tx >= ntx || ty < 0 || ty >= nty {
+		return FormatError("invalid tile coordinate")
+	}
+
+	rect := image.Rect(tx*t.XSize, ty*t.YSize, (tx+1)*t.XSize, (ty+1)*t.YSize).Intersect(lr)
+
+	img, ok := levels[image.Pt(lx, ly)]
+	if !ok {
+		if chunk.Size < 0 {
+			return FormatError("invalid chunk size")
+		}
+		_, err := io.CopyN(ioutil.Discard, d.r, int64(chunk.Size))
+		return err
+	}
+
+	raw, err := d.readBlock(rect, chunk.Size)
+	if err != nil {
+		return err
+	}
+
+	d.decodeBlock(img, rect, raw)
+	return nil
+}
+
+// newImage allocates an image of the given bounds matching the channels types.
+func (d *decoder) newImage(r image.Rectangle) hdr.Image {
+	alpha := d.alpha()
+	for _, c := range d.h.Channels {
+		if c.PixelType == PixelTypeUint {
+			if alpha {
+				return hdr.NewRGBA64(r)
+			}
+			return hdr.NewRGB64(r)
+		}
+	}
+
+	if alpha {
+		return hdr.NewRGBA(r)
+	}
+	return hdr.NewRGB(r)
+}
+
+// alpha returns true when the image has an alpha channel.
+func (d *decoder) alpha() bool {
+	for _, c := range d.h.Channels {
+		if c.Name == "A" {
+			return true
+		}
+	}
+	return false
+}
+
+// decode reads all the chunks. When all is false, only the full resolution level is decoded.
+func (d *decoder) decode(all bool) ([]Level, error) {
+	if !d.tiled() {
+		img := d.newImage(image.Rect(0, 0, d.config.Width, d.config.Height))
+
+		// Chunks are read in file order, each one knows its coordinate.
+		lpb := d.h.Compression.linesPerBlock()
+		nbOfChunks := (d.config.Height + lpb - 1) / lpb
+
+		// Offset table
+		if _, err := io.CopyN(ioutil.Discard, d.r, int64(8*nbOfChunks)); err != nil {
+			return nil, err
+		}
+
+		for i := 0; i < nbOfChunks; i++ {
+			if err := d.readChunk(img); err != nil {
+				return nil, err
+			}
+		}
+
+		return []Level{{Image: img}}, nil
+	}
+
+	t := d.h.Tiles
+	var levels []Level
+	images := map[image.Point]hdr.Image{}
+	nbOfChunks := 0
+
+	nlx, nly := t.numLevels(d.config.Width, d.config.Height)
+	for ly := 0; ly < nly; ly++ {
+		for lx := 0; lx < nlx; lx++ {
+			if t.LevelMode != LevelModeRipmap && lx != ly {
+				continue
+			}
+
+			lr := t.levelRect(d.config.Width, d.config.Height, lx, ly)
+			ntx, nty := t.numTiles(lr)
+			nbOfChunks += ntx * nty
+
+			if all || (lx == 0 && ly == 0) {
+				img := d.newImage(lr)
+				images[image.Pt(lx, ly)] = img
+				levels = append(levels, Level{X: lx, Y: ly, Image: img})
+			}
+		}
+	}
+
+	// Offset table
+	if _, err := io.CopyN(ioutil.Discard, d.r, int64(8*nbOfChunks)); err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < nbOfChunks; i++ {
+		if err := d.readTile(images); err != nil {
+			return nil, err
+		}
+	}
+
+	return levels, nil
+}
+
+//--------------------------------------//
+// Reader                               //
+//--------------------------------------//
+
+// DecodeConfig returns the color model and dimensions of an OpenEXR image without
+// decoding the entire image.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	d, err := newDecoder(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	return d.config, nil
+}
+
+// Decode reads an OpenEXR image from r and returns an image.Image.
+// HALF and FLOAT channels are decoded in a *hdr.RGB and UINT channels in a *hdr.RGB64,
+// or in a *hdr.RGBA and a *hdr.RGBA64 when the image has an alpha channel.
+// Only the full resolution level of multi-resolution images is decoded.
+func Decode(r io.Reader) (image.Image, error) {
+	d, err := newDecoder(r)
+	if err != nil {
+		return nil, err
+	}
+
+	levels, err := d.decode(false)
+	if err != nil {
+		return nil, err
+	}
+
+	return levels[0].Image, nil
+}
+
+// DecodeLevels reads an OpenEXR image from r and returns all its resolution levels
+// in file order. Scanline and single level images return only one level.
+func DecodeLevels(r io.Reader) ([]Level, error) {
+	d, err := newDecoder(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.decode(true)
+}
+
+func init() {
+	image.RegisterFormat("exr", header, Decode, DecodeConfig)
+}
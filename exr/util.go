@@ -0,0 +1,45 @@
+package exr
+
+import (
+	"bytes"
+	"io"
+)
+
+func readUntil(r io.Reader, delimiter byte) (string, error) {
+	buf := &bytes.Buffer{}
+	p := make([]byte, 1)
+
+	for {
+		if _, err := r.Read(p); err != nil {
+			return "", err
+		}
+
+		if p[0] != delimiter {
+			buf.Write(p)
+		} else {
+			return buf.String(), nil
+		}
+	}
+}
+
+// A FormatError reports that the input is not a valid OpenEXR image.
+type FormatError string
+
+func (e FormatError) Error() string {
+	return "exr: invalid format: " + string(e)
+}
+
+// An UnsupportedError reports that the input uses a valid but
+// unimplemented feature.
+type UnsupportedError string
+
+func (e UnsupportedError) Error() string {
+	return "exr: unsupported feature: " + string(e)
+}
+
+// An InternalError reports that an internal error was encountered.
+type InternalError string
+
+func (e InternalError) Error() string {
+	return "exr: internal error: " + string(e)
+}
@@ -0,0 +1,195 @@
+package exr
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"image"
+	"io"
+	"math"
+
+	"github.com/Xyzyx101/hdr"
+	"github.com/Xyzyx101/hdr/format"
+	"github.com/Xyzyx101/hdr/hdrcolor"
+)
+
+// Options are the encoding parameters.
+type Options struct {
+	Compression Compression
+	PixelType   PixelType
+}
+
+// DefaultOptions offers a good trade off between size and quality (ZIP compressed HALF channels).
+var DefaultOptions = &Options{
+	Compression: CompressionZIP,
+	PixelType:   PixelTypeHalf,
+}
+
+type encoder struct {
+	w io.Writer
+	m hdr.Image
+	h *Header
+}
+
+func newEncoder(w io.Writer, m hdr.Image, o *Options) *encoder {
+	b := image.Rect(0, 0, m.Bounds().Dx(), m.Bounds().Dy())
+
+	// Channels must be sorted by name
+	channels := []Channel{
+		{Name: "B", PixelType: o.PixelType, XSampling: 1, YSampling: 1},
+		{Name: "G", PixelType: o.PixelType, XSampling: 1, YSampling: 1},
+		{Name: "R", PixelType: o.PixelType, XSampling: 1, YSampling: 1},
+	}
+	switch m.ColorModel() {
+	case hdrcolor.RGBAModel, hdrcolor.XYZAModel:
+		channels = append([]Channel{{Name: "A", PixelType: o.PixelType, XSampling: 1, YSampling: 1}}, channels...)
+	}
+
+	return &encoder{
+		w: w,
+		m: m,
+		h: &Header{
+			Channels:          channels,
+			Compression:       o.Compression,
+			DataWindow:        b,
+			DisplayWindow:     b,
+			LineOrder:         LineOrderIncreasingY,
+			PixelAspectRatio:  1,
+			ScreenWindowWidth: 1,
+		},
+	}
+}
+
+//--------------------------------------//
+// Header writer                        //
+//--------------------------------------//
+
+func (e *encoder) writeHeader(w io.Writer) error {
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+
+	if err := binary.Write(w, binary.LittleEndian, uint32(version)); err != nil {
+		return err
+	}
+
+	return e.h.writeTo(w)
+}
+
+//--------------------------------------//
+// Pixels writer                        //
+//--------------------------------------//
+
+// encodeBlock returns the raw data of the given lines.
+func (e *encoder) encodeBlock(y1, y2 int) []byte {
+	b := e.m.Bounds()
+	w := b.Dx()
+
+	raw := make([]byte, 0, (y2-y1)*w*len(e.h.Channels)*4)
+	value := make([]byte, 4)
+	line := make([][4]float64, w)
+	first := 4 - len(e.h.Channels) // Without alpha, the channels start at blue
+
+	for y := y1; y < y2; y++ {
+		for x := 0; x < w; x++ {
+			r, g, bb, a := e.m.HDRAt(b.Min.X+x, b.Min.Y+y).HDRRGBA()
+			line[x] = [4]float64{a, bb, g, r} // Channels order
+		}
+
+		for c, ch := range e.h.Channels {
+			for x := 0; x < w; x++ {
+				v := float32(line[x][first+c])
+
+				switch ch.PixelType {
+				case PixelTypeHalf:
+					binary.LittleEndian.PutUint16(value, format.FloatToHalf(v))
+					raw = append(raw, value[:2]...)
+				case PixelTypeFloat:
+					binary.LittleEndian.PutUint32(value, math.Float32bits(v))
+					raw = append(raw, value...)
+				}
+			}
+		}
+	}
+
+	return raw
+}
+
+func (e *encoder) encode() error {
+	h := e.m.Bounds().Dy()
+	lpb := e.h.Compression.linesPerBlock()
+	nbOfChunks := (h + lpb - 1) / lpb
+
+	// Compress all chunks first in order to build the offset table.
+	chunks := make([][]byte, nbOfChunks)
+	for i := range chunks {
+		y1 := i * lpb
+		y2 := y1 + lpb
+		if y2 > h {
+			y2 = h
+		}
+
+		var err error
+		chunks[i], err = compress(e.h.Compression, e.encodeBlock(y1, y2))
+		if err != nil {
+			return err
+		}
+	}
+
+	head := &bytes.Buffer{}
+	if err := e.writeHeader(head); err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(e.w)
+	if _, err := w.Write(head.Bytes()); err != nil {
+		return err
+	}
+
+	// Offset table
+	offset := uint64(head.Len() + 8*nbOfChunks)
+	for _, chunk := range chunks {
+		if err := binary.Write(w, binary.LittleEndian, offset); err != nil {
+			return err
+		}
+		offset += uint64(8 + len(chunk))
+	}
+
+	// Chunks
+	for i, chunk := range chunks {
+		if err := binary.Write(w, binary.LittleEndian, [2]int32{int32(i * lpb), int32(len(chunk))}); err != nil {
+			return err
+		}
+		if _, err := w.Write(chunk); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+// Encode writes the Image m to w in OpenEXR format with DefaultOptions.
+func Encode(w io.Writer, m hdr.Image) error {
+	return EncodeWithOptions(w, m, DefaultOptions)
+}
+
+// EncodeWithOptions writes the Image m to w in OpenEXR format.
+func EncodeWithOptions(w io.Writer, m hdr.Image, o *Options) error {
+	switch o.Compression {
+	case CompressionNone, CompressionRLE, CompressionZIPS, CompressionZIP:
+	default:
+		return UnsupportedError("compression")
+	}
+
+	switch o.PixelType {
+	case PixelTypeHalf, PixelTypeFloat:
+	default:
+		return UnsupportedError("pixel type")
+	}
+
+	if m.Bounds().Empty() {
+		return FormatError("empty image")
+	}
+
+	return newEncoder(w, m, o).encode()
+}
@@ -0,0 +1,51 @@
+package filter
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/Xyzyx101/hdr"
+	"github.com/Xyzyx101/hdr/hdrcolor"
+)
+
+// A GamutMapping converts on the fly the pixels of an image to a color space and maps them into its gamut.
+type GamutMapping struct {
+	HDRImage hdr.Image
+	Space    *hdrcolor.ColorSpace
+	Mapping  *hdrcolor.GamutMapping
+}
+
+// NewGamutMapping instanciates a new GamutMapping of img to the color space cs with the gamut mapping gm.
+// Colors are adapted to the white point of cs with the Bradford transform.
+func NewGamutMapping(img hdr.Image, cs *hdrcolor.ColorSpace, gm *hdrcolor.GamutMapping) *GamutMapping {
+	return &GamutMapping{
+		HDRImage: img,
+		Space:    cs,
+		Mapping:  gm,
+	}
+}
+
+// ColorModel returns the RGB color model.
+func (f *GamutMapping) ColorModel() color.Model {
+	return hdrcolor.RGBModel
+}
+
+// Bounds delegates to HDRImage function.
+func (f *GamutMapping) Bounds() image.Rectangle {
+	return f.HDRImage.Bounds()
+}
+
+// Size implements Image.
+func (f *GamutMapping) Size() int {
+	return f.Bounds().Dx() * f.Bounds().Dy()
+}
+
+// At implements Image with gamut mapping on HDRImage.
+func (f *GamutMapping) At(x, y int) color.Color {
+	return f.HDRAt(x, y)
+}
+
+// HDRAt implements Image with gamut mapping on HDRImage.
+func (f *GamutMapping) HDRAt(x, y int) hdrcolor.Color {
+	return f.Space.MapGamut(f.Space.From(f.HDRImage.HDRAt(x, y)), f.Mapping)
+}
@@ -39,3 +39,23 @@ func float32frombytes(bytes []byte) float32 {
 
 	return float
 }
+
+// ToHalfBytes converts given float64 values to their half-precision bytes representation.
+func ToHalfBytes(f1, f2, f3 float64) []byte {
+	pixel := make([]byte, 3*2)
+
+	binary.LittleEndian.PutUint16(pixel[0:2], FloatToHalf(float32(f1)))
+	binary.LittleEndian.PutUint16(pixel[2:4], FloatToHalf(float32(f2)))
+	binary.LittleEndian.PutUint16(pixel[4:6], FloatToHalf(float32(f3)))
+
+	return pixel
+}
+
+// FromHalfBytes converts given half-precision bytes to their float64 values.
+func FromHalfBytes(pixel []byte) (float64, float64, float64) {
+	f1 := HalfToFloat(binary.LittleEndian.Uint16(pixel[0:2]))
+	f2 := HalfToFloat(binary.LittleEndian.Uint16(pixel[2:4]))
+	f3 := HalfToFloat(binary.LittleEndian.Uint16(pixel[4:6]))
+
+	return float64(f1), float64(f2), float64(f3)
+}
@@ -0,0 +1,95 @@
+package format
+
+import (
+	"math"
+	"sync"
+)
+
+// FloatToHalf converts a float 32 bits to its IEEE 754 half-precision (binary16) representation.
+// The value is rounded to the nearest half, ties to even.
+// Denormals, infinities and NaN are preserved, overflows become infinities.
+func FloatToHalf(f float32) uint16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int(bits>>23) & 0xff
+	mant := bits & 0x7fffff
+
+	if exp == 0xff {
+		if mant == 0 {
+			return sign | 0x7c00 // Infinity
+		}
+		return sign | 0x7e00 | uint16(mant>>13) // Quiet NaN
+	}
+
+	e := exp - 127 + 15
+	if e >= 0x1f {
+		return sign | 0x7c00 // Overflow
+	}
+
+	if e <= 0 {
+		// Half denormal or zero
+		if e < -10 {
+			return sign
+		}
+		mant |= 0x800000 // Implicit leading bit
+		shift := uint32(14 - e)
+		h := mant >> shift
+		rem := mant & (1<<shift - 1)
+		halfway := uint32(1) << (shift - 1)
+		if rem > halfway || (rem == halfway && h&1 == 1) {
+			h++
+		}
+		return sign | uint16(h)
+	}
+
+	h := uint32(e)<<10 | mant>>13
+	rem := mant & 0x1fff
+	if rem > 0x1000 || (rem == 0x1000 && h&1 == 1) {
+		h++ // May carry into the exponent, up to infinity
+	}
+	return sign | uint16(h)
+}
+
+var (
+	halfTableOnce sync.Once
+	halfTable     []float32
+)
+
+// HalfToFloat converts an IEEE 754 half-precision (binary16) value to a float 32 bits.
+// The conversion is lossless and uses a lookup table of all the halves (256 KiB) built on first use.
+func HalfToFloat(h uint16) float32 {
+	halfTableOnce.Do(func() {
+		halfTable = make([]float32, 1<<16)
+		for i := range halfTable {
+			halfTable[i] = halfToFloat(uint16(i))
+		}
+	})
+
+	return halfTable[h]
+}
+
+// halfToFloat computes the conversion of HalfToFloat.
+func halfToFloat(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+
+	switch exp {
+	case 0:
+		if mant == 0 {
+			return math.Float32frombits(sign) // Signed zero
+		}
+		// Denormal, normalize it
+		e := uint32(127 - 15 + 1)
+		for mant&0x400 == 0 {
+			mant <<= 1
+			e--
+		}
+		mant &= 0x3ff
+		return math.Float32frombits(sign | e<<23 | mant<<13)
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13) // Infinity or NaN
+	}
+
+	return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+}
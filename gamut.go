@@ -0,0 +1,27 @@
+package hdr
+
+import (
+	"github.com/Xyzyx101/hdr/hdrcolor"
+)
+
+// MapGamut returns a copy of m converted to the color space cs with the Bradford transform (see ConvertColorSpaceWithCAT)
+// whose out-of-gamut pixels, with negative values, are mapped into the gamut of cs with gm.
+func MapGamut(m Image, cs *hdrcolor.ColorSpace, gm *hdrcolor.GamutMapping) Image {
+	dst := ConvertColorSpaceWithCAT(m, cs, hdrcolor.Bradford)
+
+	switch img := dst.(type) {
+	case *RGB:
+		mapGamut(img.Pix, 3, cs, gm)
+	case *RGBA:
+		mapGamut(img.Pix, 4, cs, gm)
+	}
+
+	return dst
+}
+
+func mapGamut(pix []float32, n int, cs *hdrcolor.ColorSpace, gm *hdrcolor.GamutMapping) {
+	for i := 0; i+2 < len(pix); i += n {
+		c := cs.MapGamut(hdrcolor.RGB{R: float64(pix[i]), G: float64(pix[i+1]), B: float64(pix[i+2])}, gm)
+		pix[i], pix[i+1], pix[i+2] = float32(c.R), float32(c.G), float32(c.B)
+	}
+}
@@ -0,0 +1,199 @@
+package hdr10
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+	"math"
+
+	"github.com/Xyzyx101/hdr"
+	"github.com/Xyzyx101/hdr/hdrcolor"
+	"github.com/Xyzyx101/hdr/util"
+)
+
+// Options are the encoding parameters.
+type Options struct {
+	// Transfer is hdrcolor.TransferPQ (HDR10) or hdrcolor.TransferHLG.
+	Transfer *hdrcolor.Transfer
+	// PeakLuminance is the peak luminance in cd/m² of the mastering display, brighter pixels are clipped.
+	PeakLuminance float64
+	// WhiteLuminance is the luminance in cd/m² of the relative luminance Y = 1 of the image.
+	WhiteLuminance float64
+	// BitDepth is the depth of the raw frames (10, 12 or 16 bits), stored in the least significant bits of 16-bit words.
+	BitDepth int
+}
+
+// DefaultOptions are the HDR10 parameters of a 1000 cd/m² mastering display
+// with the reference white of ITU-R BT.2408 (203 cd/m²) and 10-bit raw frames.
+var DefaultOptions = &Options{
+	Transfer:       hdrcolor.TransferPQ,
+	PeakLuminance:  1000,
+	WhiteLuminance: 203,
+	BitDepth:       10,
+}
+
+func (o *Options) validate() error {
+	if o.Transfer != hdrcolor.TransferPQ && o.Transfer != hdrcolor.TransferHLG {
+		return UnsupportedError("transfer function")
+	}
+	if o.PeakLuminance <= 0 || o.PeakLuminance > 10000 {
+		return UnsupportedError("peak luminance")
+	}
+	if o.WhiteLuminance <= 0 {
+		return UnsupportedError("white luminance")
+	}
+	switch o.BitDepth {
+	case 10, 12, 16:
+	default:
+		return UnsupportedError("bit depth")
+	}
+	return nil
+}
+
+//--------------------------------------//
+// Signals                              //
+//--------------------------------------//
+
+// signals calls f with the non-linear Rec.2020 signals, within [0, 1], of each pixel of m.
+// f is called concurrently.
+func signals(m hdr.Image, o *Options, f func(x, y int, r, g, b float64)) {
+	b := m.Bounds()
+
+	<-util.ParallelR(b, func(x1, y1, x2, y2 int) {
+		for y := b.Min.Y + y1; y < b.Min.Y+y2; y++ {
+			for x := b.Min.X + x1; x < b.Min.X+x2; x++ {
+				c := hdrcolor.Rec2020.From(m.HDRAt(x, y))
+				r, g, bb := c.R, c.G, c.B
+
+				// Display light
+				r = clip(r*o.WhiteLuminance, o.PeakLuminance)
+				g = clip(g*o.WhiteLuminance, o.PeakLuminance)
+				bb = clip(bb*o.WhiteLuminance, o.PeakLuminance)
+
+				if o.Transfer == hdrcolor.TransferHLG {
+					r, g, bb = hdrcolor.HLGInverseEOTF(r, g, bb, o.PeakLuminance)
+				} else {
+					r, g, bb = hdrcolor.PQEncode(r), hdrcolor.PQEncode(g), hdrcolor.PQEncode(bb)
+				}
+
+				f(x, y, r, g, bb)
+			}
+		}
+	})
+}
+
+func clip(v, max float64) float64 {
+	return math.Max(0, math.Min(v, max))
+}
+
+func quantize(v float64, max float64) uint16 {
+	return uint16(clip(v, 1)*max + 0.5)
+}
+
+// Convert returns the non-linear Rec.2020 signals of m on 16 bits.
+// Pixels are converted from the color space of m and their luminance is clipped to the peak luminance.
+func Convert(m hdr.Image, o *Options) (*image.RGBA64, error) {
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+
+	img := image.NewRGBA64(m.Bounds())
+	signals(m, o, func(x, y int, r, g, b float64) {
+		i := img.PixOffset(x, y)
+		binary.BigEndian.PutUint16(img.Pix[i:], quantize(r, 0xFFFF))
+		binary.BigEndian.PutUint16(img.Pix[i+2:], quantize(g, 0xFFFF))
+		binary.BigEndian.PutUint16(img.Pix[i+4:], quantize(b, 0xFFFF))
+		binary.BigEndian.PutUint16(img.Pix[i+6:], 0xFFFF)
+	})
+
+	return img, nil
+}
+
+//--------------------------------------//
+// Writers                              //
+//--------------------------------------//
+
+// EncodePNG writes m to w as a 16-bit PNG of Rec.2020 PQ or HLG signals.
+// The color space is signaled with a cICP chunk.
+func EncodePNG(w io.Writer, m hdr.Image, o *Options) error {
+	img, err := Convert(m, o)
+	if err != nil {
+		return err
+	}
+
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, img); err != nil {
+		return err
+	}
+	raw := buf.Bytes()
+
+	// cICP after the signature and the IHDR chunk
+	const ihdrEnd = 8 + 4 + 4 + 13 + 4
+	if _, err := w.Write(raw[:ihdrEnd]); err != nil {
+		return err
+	}
+	if err := writeCICP(w, o); err != nil {
+		return err
+	}
+	_, err = w.Write(raw[ihdrEnd:])
+	return err
+}
+
+// writeCICP writes the coding-independent code points (ITU-T H.273) chunk of the PNG.
+func writeCICP(w io.Writer, o *Options) error {
+	transfer := byte(16) // SMPTE ST 2084
+	if o.Transfer == hdrcolor.TransferHLG {
+		transfer = 18 // ARIB STD-B67
+	}
+	// BT.2020 primaries, transfer, RGB matrix, full range
+	chunk := []byte{'c', 'I', 'C', 'P', 9, transfer, 0, 1}
+
+	var head [4]byte
+	binary.BigEndian.PutUint32(head[:], uint32(len(chunk)-4))
+	if _, err := w.Write(head[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(chunk); err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint32(head[:], crc32.ChecksumIEEE(chunk))
+	_, err := w.Write(head[:])
+	return err
+}
+
+// EncodeRaw writes m to w as a raw frame of Rec.2020 PQ or HLG signals:
+// interleaved R, G, B 16-bit little endian words from top to bottom, holding values of o.BitDepth bits (full range).
+func EncodeRaw(w io.Writer, m hdr.Image, o *Options) error {
+	if err := o.validate(); err != nil {
+		return err
+	}
+
+	b := m.Bounds()
+	max := float64(int(1)<<uint(o.BitDepth) - 1)
+	frame := make([]byte, 6*b.Dx()*b.Dy())
+
+	signals(m, o, func(x, y int, r, g, bb float64) {
+		i := 6 * ((y-b.Min.Y)*b.Dx() + x - b.Min.X)
+		binary.LittleEndian.PutUint16(frame[i:], quantize(r, max))
+		binary.LittleEndian.PutUint16(frame[i+2:], quantize(g, max))
+		binary.LittleEndian.PutUint16(frame[i+4:], quantize(bb, max))
+	})
+
+	bw := bufio.NewWriter(w)
+	if _, err := bw.Write(frame); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// An UnsupportedError reports that the options use a valid but
+// unimplemented feature.
+type UnsupportedError string
+
+func (e UnsupportedError) Error() string {
+	return "hdr10: unsupported feature: " + string(e)
+}
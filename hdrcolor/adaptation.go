@@ -0,0 +1,83 @@
+package hdrcolor
+
+// A CAT is a chromatic adaptation transform, defined by the matrix converting XYZ
+// to the cone response domain where the white points are scaled (von Kries model).
+type CAT struct {
+	Name   string
+	Matrix Matrix
+}
+
+// Chromatic adaptation transforms.
+var (
+	// Bradford is the Bradford transform, used by ICC profiles.
+	Bradford = &CAT{"Bradford", Matrix{
+		{0.8951, 0.2664, -0.1614},
+		{-0.7502, 1.7135, 0.0367},
+		{0.0389, -0.0685, 1.0296},
+	}}
+	// CAT02 is the transform of the CIECAM02 color appearance model.
+	CAT02 = &CAT{"CAT02", Matrix{
+		{0.7328, 0.4296, -0.1624},
+		{-0.7036, 1.6975, 0.0061},
+		{0.0030, 0.0136, 0.9834},
+	}}
+	// CAT16 is the transform of the CAM16 color appearance model.
+	CAT16 = &CAT{"CAT16", Matrix{
+		{0.401288, 0.650173, -0.051461},
+		{-0.250268, 1.204414, 0.045854},
+		{-0.002079, 0.048952, 0.953127},
+	}}
+	// VonKries is the von Kries transform with the Hunt-Pointer-Estévez cone responses (normalized to D65).
+	VonKries = &CAT{"von Kries", Matrix{
+		{0.4002400, 0.7076000, -0.0808100},
+		{-0.2263000, 1.1653200, 0.0457000},
+		{0.0000000, 0.0000000, 0.9182200},
+	}}
+	// XYZScaling scales the XYZ values, the simplest and least accurate transform.
+	XYZScaling = &CAT{"XYZ scaling", IdentityMatrix}
+)
+
+// Illuminants, in addition to the white points D50 and D65.
+var (
+	// D55 is the CIE standard illuminant D55 (mid-morning daylight).
+	D55 = Chromaticity{0.3324, 0.3474}
+	// D60 is the CIE illuminant D60.
+	D60 = Chromaticity{0.3217, 0.3378}
+	// IlluminantA is the CIE standard illuminant A (tungsten lamp, 2856 K).
+	IlluminantA = Chromaticity{0.44757, 0.40745}
+)
+
+// AdaptationMatrix returns the matrix adapting XYZ colors seen under the white point src
+// to the white point dst with the transform cat.
+func AdaptationMatrix(cat *CAT, src, dst Chromaticity) Matrix {
+	if src == dst {
+		return IdentityMatrix
+	}
+
+	m := cat.Matrix
+	s0, s1, s2 := m.Apply(src.XYZ())
+	d0, d1, d2 := m.Apply(dst.XYZ())
+
+	scale := Matrix{
+		{d0 / s0, 0, 0},
+		{0, d1 / s1, 0},
+		{0, 0, d2 / s2},
+	}
+	return m.Inverse().Mul(scale).Mul(m)
+}
+
+// Adapt adapts the XYZ color c seen under the white point src to the white point dst with the transform cat.
+func Adapt(c XYZ, cat *CAT, src, dst Chromaticity) XYZ {
+	x, y, z := AdaptationMatrix(cat, src, dst).Apply(c.X, c.Y, c.Z)
+	return XYZ{X: x, Y: y, Z: z}
+}
+
+// AdaptedConversionMatrix is like ConversionMatrix with a chromatic adaptation
+// between the white points of the color spaces with the transform cat.
+// The white of the color space is then the white of the color space to.
+func (cs *ColorSpace) AdaptedConversionMatrix(to *ColorSpace, cat *CAT) Matrix {
+	if cs == to {
+		return IdentityMatrix
+	}
+	return to.fromXYZ.Mul(AdaptationMatrix(cat, cs.White, to.White)).Mul(cs.toXYZ)
+}
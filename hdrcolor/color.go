@@ -7,42 +7,42 @@ import (
 )
 
 // Color can convert itself to alpha-premultiplied 16-bits per channel RGBA and HDR float64 RGB.
-// The conversion may be lossy.
+// The conversion may be lossy: the 16-bits values are clamped and sRGB encoded (see ToLDR).
 type Color interface {
 	color.Color
 
-	// HDRRGBA returns the red, green, blue and alpha values
-	// for the HDR color.
+	// HDRRGBA returns the linear Rec.709 red, green, blue and alpha values
+	// for the HDR color. Alpha ranges within [0, 1] and the colors are not alpha-premultiplied.
 	HDRRGBA() (r, g, b, a float64)
 
-	// HDRXYZA returns the x, y, z and alpha values
-	// for the HDR color.
+	// HDRXYZA returns the x, y, z (relative to D65) and alpha values
+	// for the HDR color. Alpha ranges within [0, 1] and the colors are not alpha-premultiplied.
 	HDRXYZA() (x, y, z, a float64)
 }
 
 // RGB represents a HDR color in RGB color-space.
 type RGB struct {
 	R, G, B float64
+	// Space is the color space of the linear values, nil for Rec.709.
+	Space *ColorSpace
 }
 
 // RGBA returns the alpha-premultiplied red, green, blue and alpha values
 // for the color. Each value ranges within [0, 0xffff], but is represented
 // by a uint32 so that multiplying by a blend factor up to 0xffff will not
-// overflow.
+// overflow. The values are clamped and sRGB encoded.
 func (c RGB) RGBA() (r, g, b, a uint32) {
-	r = uint32(c.R * 0xFFFF)
-	g = uint32(c.G * 0xFFFF)
-	b = uint32(c.B * 0xFFFF)
-	a = 0xFFFF
-
-	return
+	return ldrRGBA(c.HDRRGBA())
 }
 
 // HDRRGBA returns the red, green, blue and alpha values
-// for the HDR color.
+// for the HDR color, converted to Rec.709 from its color space.
 func (c RGB) HDRRGBA() (r, g, b, a float64) {
 	r, g, b = c.R, c.G, c.B
-	a = 0xFFFF
+	if !isRec709(c.Space) {
+		r, g, b = Rec709.fromD65.Apply(c.Space.toD65.Apply(r, g, b))
+	}
+	a = 1
 
 	return
 }
@@ -50,8 +50,8 @@ func (c RGB) HDRRGBA() (r, g, b, a float64) {
 // HDRXYZA returns the x, y, z and alpha values
 // for the HDR color.
 func (c RGB) HDRXYZA() (x, y, z, a float64) {
-	x, y, z = colorful.LinearRgbToXyz(c.R, c.G, c.B)
-	a = 0xFFFF
+	x, y, z = toXYZD65(c.Space, c.R, c.G, c.B)
+	a = 1
 
 	return
 }
@@ -64,23 +64,16 @@ type XYZ struct {
 // RGBA returns the alpha-premultiplied red, green, blue and alpha values
 // for the color. Each value ranges within [0, 0xffff], but is represented
 // by a uint32 so that multiplying by a blend factor up to 0xffff will not
-// overflow.
+// overflow. The values are clamped and sRGB encoded.
 func (c XYZ) RGBA() (r, g, b, a uint32) {
-	rr, gg, bb, aa := c.HDRRGBA()
-	r = uint32(rr * 0xFFFF)
-	g = uint32(gg * 0xFFFF)
-	b = uint32(bb * 0xFFFF)
-	a = uint32(aa * 0xFFFF)
-
-	return
+	return ldrRGBA(c.HDRRGBA())
 }
 
 // HDRRGBA returns the red, green, blue and alpha values
 // for the HDR color.
 func (c XYZ) HDRRGBA() (r, g, b, a float64) {
 	r, g, b = colorful.XyzToLinearRgb(c.X, c.Y, c.Z)
-
-	a = 0xFFFF
+	a = 1
 
 	return
 }
@@ -89,15 +82,115 @@ func (c XYZ) HDRRGBA() (r, g, b, a float64) {
 // for the HDR color.
 func (c XYZ) HDRXYZA() (x, y, z, a float64) {
 	x, y, z = c.X, c.Y, c.Z
-	a = 0xFFFF
+	a = 1
+
+	return
+}
+
+// RGBA represents a HDR color in RGB color-space with a float alpha ranging within [0, 1].
+// The color is not alpha-premultiplied.
+type RGBA struct {
+	R, G, B, A float64
+	// Space is the color space of the linear values, nil for Rec.709.
+	Space *ColorSpace
+}
+
+// RGBA returns the alpha-premultiplied red, green, blue and alpha values
+// for the color. Each value ranges within [0, 0xffff], but is represented
+// by a uint32 so that multiplying by a blend factor up to 0xffff will not
+// overflow. The values are clamped and sRGB encoded.
+func (c RGBA) RGBA() (r, g, b, a uint32) {
+	return ldrRGBA(c.HDRRGBA())
+}
+
+// HDRRGBA returns the red, green, blue and alpha values
+// for the HDR color, converted to Rec.709 from its color space.
+func (c RGBA) HDRRGBA() (r, g, b, a float64) {
+	r, g, b, _ = RGB{R: c.R, G: c.G, B: c.B, Space: c.Space}.HDRRGBA()
+	return r, g, b, c.A
+}
+
+// HDRXYZA returns the x, y, z and alpha values
+// for the HDR color.
+func (c RGBA) HDRXYZA() (x, y, z, a float64) {
+	x, y, z = toXYZD65(c.Space, c.R, c.G, c.B)
+	a = c.A
+
+	return
+}
+
+// XYZA represents a HDR color in XYZ color-space with a float alpha ranging within [0, 1].
+// The color is not alpha-premultiplied.
+type XYZA struct {
+	X, Y, Z, A float64
+}
+
+// RGBA returns the alpha-premultiplied red, green, blue and alpha values
+// for the color. Each value ranges within [0, 0xffff], but is represented
+// by a uint32 so that multiplying by a blend factor up to 0xffff will not
+// overflow. The values are clamped and sRGB encoded.
+func (c XYZA) RGBA() (r, g, b, a uint32) {
+	return ldrRGBA(c.HDRRGBA())
+}
+
+// HDRRGBA returns the red, green, blue and alpha values
+// for the HDR color.
+func (c XYZA) HDRRGBA() (r, g, b, a float64) {
+	r, g, b = colorful.XyzToLinearRgb(c.X, c.Y, c.Z)
+	a = c.A
 
 	return
 }
 
+// HDRXYZA returns the x, y, z and alpha values
+// for the HDR color.
+func (c XYZA) HDRXYZA() (x, y, z, a float64) {
+	return c.X, c.Y, c.Z, c.A
+}
+
+// Premultiply returns the color with its red, green and blue values multiplied by its alpha.
+func (c RGBA) Premultiply() RGBA {
+	return RGBA{R: c.R * c.A, G: c.G * c.A, B: c.B * c.A, A: c.A, Space: c.Space}
+}
+
+// Unpremultiply returns the color with its alpha-premultiplied red, green and blue values divided by its alpha.
+func (c RGBA) Unpremultiply() RGBA {
+	if c.A == 0 {
+		return RGBA{Space: c.Space}
+	}
+	return RGBA{R: c.R / c.A, G: c.G / c.A, B: c.B / c.A, A: c.A, Space: c.Space}
+}
+
+// Premultiply returns the color with its x, y and z values multiplied by its alpha.
+func (c XYZA) Premultiply() XYZA {
+	return XYZA{X: c.X * c.A, Y: c.Y * c.A, Z: c.Z * c.A, A: c.A}
+}
+
+// Unpremultiply returns the color with its alpha-premultiplied x, y and z values divided by its alpha.
+func (c XYZA) Unpremultiply() XYZA {
+	if c.A == 0 {
+		return XYZA{}
+	}
+	return XYZA{X: c.X / c.A, Y: c.Y / c.A, Z: c.Z / c.A, A: c.A}
+}
+
+// Alpha returns the float alpha of c ranging within [0, 1], 1 for colors without alpha.
+func Alpha(c color.Color) float64 {
+	if hdrc, ok := c.(Color); ok {
+		_, _, _, a := hdrc.HDRRGBA()
+		return a
+	}
+
+	_, _, _, a := c.RGBA()
+	return float64(a) / 0xFFFF
+}
+
 // Models for the standard color types.
 var (
-	RGBModel color.Model = color.ModelFunc(rgbModel)
-	XYZModel color.Model = color.ModelFunc(xyzModel)
+	RGBModel  color.Model = color.ModelFunc(rgbModel)
+	XYZModel  color.Model = color.ModelFunc(xyzModel)
+	RGBAModel color.Model = color.ModelFunc(rgbaModel)
+	XYZAModel color.Model = color.ModelFunc(xyzaModel)
 )
 
 func rgbModel(c color.Color) color.Color {
@@ -106,6 +199,11 @@ func rgbModel(c color.Color) color.Color {
 		return c
 	}
 
+	if rgba, ok := c.(RGBA); ok {
+		// Same color space
+		return RGB{R: rgba.R, G: rgba.G, B: rgba.B, Space: rgba.Space}
+	}
+
 	if hdrc, ok := c.(Color); ok {
 		// HDR color
 		r, g, b, _ := hdrc.HDRRGBA()
@@ -113,8 +211,8 @@ func rgbModel(c color.Color) color.Color {
 	}
 
 	// LDR color
-	r, g, b, _ := c.RGBA()
-	return RGB{R: float64(r), G: float64(g), B: float64(b)}
+	r, g, b := hdrRGB(c)
+	return RGB{R: r, G: g, B: b}
 }
 
 func xyzModel(c color.Color) color.Color {
@@ -131,7 +229,46 @@ func xyzModel(c color.Color) color.Color {
 	}
 
 	// LDR color
-	r, g, b, _ := c.RGBA()
-	x, y, z := colorful.LinearRgbToXyz(float64(r), float64(g), float64(b))
+	x, y, z := colorful.LinearRgbToXyz(hdrRGB(c))
 	return XYZ{X: x, Y: y, Z: z}
 }
+
+func rgbaModel(c color.Color) color.Color {
+	if _, ok := c.(RGBA); ok {
+		// Already RGBA
+		return c
+	}
+
+	if rgb, ok := c.(RGB); ok {
+		// Same color space
+		return RGBA{R: rgb.R, G: rgb.G, B: rgb.B, A: 1, Space: rgb.Space}
+	}
+
+	if hdrc, ok := c.(Color); ok {
+		// HDR color
+		r, g, b, a := hdrc.HDRRGBA()
+		return RGBA{R: r, G: g, B: b, A: a}
+	}
+
+	// LDR color (alpha-premultiplied)
+	r, g, b, a := hdrRGBAFromLDR(c)
+	return RGBA{R: r, G: g, B: b, A: a}
+}
+
+func xyzaModel(c color.Color) color.Color {
+	if _, ok := c.(XYZA); ok {
+		// Already XYZA
+		return c
+	}
+
+	if hdrc, ok := c.(Color); ok {
+		// HDR color
+		x, y, z, a := hdrc.HDRXYZA()
+		return XYZA{X: x, Y: y, Z: z, A: a}
+	}
+
+	// LDR color
+	rgba := rgbaModel(c).(RGBA)
+	x, y, z := colorful.LinearRgbToXyz(rgba.R, rgba.G, rgba.B)
+	return XYZA{X: x, Y: y, Z: z, A: rgba.A}
+}
@@ -0,0 +1,194 @@
+package hdrcolor
+
+import colorful "github.com/lucasb-eyer/go-colorful"
+
+// A Chromaticity is a CIE 1931 xy chromaticity coordinate.
+type Chromaticity struct {
+	X, Y float64
+}
+
+// XYZ returns the tristimulus values of the chromaticity with a luminance Y of 1.
+func (c Chromaticity) XYZ() (x, y, z float64) {
+	if c.Y == 0 {
+		return 0, 0, 0
+	}
+	return c.X / c.Y, 1, (1 - c.X - c.Y) / c.Y
+}
+
+// White points.
+var (
+	// D50 is the CIE standard illuminant D50 (horizon light, ICC profile connection space).
+	D50 = Chromaticity{0.3457, 0.3585}
+	// D65 is the CIE standard illuminant D65 (noon daylight).
+	D65 = Chromaticity{0.3127, 0.3290}
+	// DCIWhite is the white point of the DCI-P3 theater projection.
+	DCIWhite = Chromaticity{0.314, 0.351}
+	// ACESWhite is the white point of the ACES color spaces (close to D60).
+	ACESWhite = Chromaticity{0.32168, 0.33767}
+)
+
+// A ColorSpace is an RGB color space defined by its primaries, white point and transfer function.
+// HDR images hold linear values, the transfer function describes their non-linear encoding.
+type ColorSpace struct {
+	Name                    string
+	Red, Green, Blue, White Chromaticity
+	Transfer                *Transfer
+
+	toXYZ   Matrix
+	fromXYZ Matrix
+	// Conversions with the XYZ of the library, relative to D65
+	toD65   Matrix
+	fromD65 Matrix
+}
+
+// NewColorSpace returns the color space of the given primaries, white point and transfer function.
+// A nil transfer is linear.
+func NewColorSpace(name string, red, green, blue, white Chromaticity, transfer *Transfer) *ColorSpace {
+	if transfer == nil {
+		transfer = TransferLinear
+	}
+
+	cs := &ColorSpace{
+		Name:     name,
+		Red:      red,
+		Green:    green,
+		Blue:     blue,
+		White:    white,
+		Transfer: transfer,
+	}
+
+	// Primaries as columns, scaled so that RGB (1, 1, 1) is the white point.
+	var p Matrix
+	for j, c := range []Chromaticity{red, green, blue} {
+		p[0][j], p[1][j], p[2][j] = c.XYZ()
+	}
+	sr, sg, sb := p.Inverse().Apply(white.XYZ())
+	for i := 0; i < 3; i++ {
+		cs.toXYZ[i] = [3]float64{p[i][0] * sr, p[i][1] * sg, p[i][2] * sb}
+	}
+	cs.fromXYZ = cs.toXYZ.Inverse()
+	cs.toD65 = AdaptationMatrix(Bradford, white, D65).Mul(cs.toXYZ)
+	cs.fromD65 = cs.toD65.Inverse()
+
+	return cs
+}
+
+// Built-in color spaces.
+var (
+	// Rec709 is the ITU-R BT.709 color space (HDTV), sharing its primaries and white point with sRGB.
+	// It is the implicit color space of RGB colors.
+	Rec709 = NewColorSpace("Rec.709",
+		Chromaticity{0.64, 0.33}, Chromaticity{0.30, 0.60}, Chromaticity{0.15, 0.06}, D65, TransferRec709)
+	// SRGB is the sRGB color space (Rec.709 primaries with the sRGB transfer function).
+	SRGB = NewColorSpace("sRGB",
+		Chromaticity{0.64, 0.33}, Chromaticity{0.30, 0.60}, Chromaticity{0.15, 0.06}, D65, TransferSRGB)
+	// Rec2020 is the ITU-R BT.2020 wide gamut color space (UHDTV).
+	Rec2020 = NewColorSpace("Rec.2020",
+		Chromaticity{0.708, 0.292}, Chromaticity{0.170, 0.797}, Chromaticity{0.131, 0.046}, D65, TransferRec709)
+	// Rec2100PQ is the ITU-R BT.2100 color space of HDR10 (Rec.2020 primaries with the PQ transfer function).
+	Rec2100PQ = NewColorSpace("Rec.2100 PQ",
+		Chromaticity{0.708, 0.292}, Chromaticity{0.170, 0.797}, Chromaticity{0.131, 0.046}, D65, TransferPQ)
+	// Rec2100HLG is the ITU-R BT.2100 color space with the HLG transfer function.
+	Rec2100HLG = NewColorSpace("Rec.2100 HLG",
+		Chromaticity{0.708, 0.292}, Chromaticity{0.170, 0.797}, Chromaticity{0.131, 0.046}, D65, TransferHLG)
+	// DCIP3 is the DCI-P3 color space of digital cinema projection.
+	DCIP3 = NewColorSpace("DCI-P3",
+		Chromaticity{0.680, 0.320}, Chromaticity{0.265, 0.690}, Chromaticity{0.150, 0.060}, DCIWhite, TransferDCI)
+	// DisplayP3 is the Display P3 color space (DCI-P3 primaries, D65 white point and sRGB transfer function).
+	DisplayP3 = NewColorSpace("Display P3",
+		Chromaticity{0.680, 0.320}, Chromaticity{0.265, 0.690}, Chromaticity{0.150, 0.060}, D65, TransferSRGB)
+	// ACES2065_1 is the ACES archival color space (AP0 primaries, linear).
+	ACES2065_1 = NewColorSpace("ACES2065-1",
+		Chromaticity{0.7347, 0.2653}, Chromaticity{0.0, 1.0}, Chromaticity{0.0001, -0.0770}, ACESWhite, TransferLinear)
+	// ACEScg is the ACES working color space for rendering and compositing (AP1 primaries, linear).
+	ACEScg = NewColorSpace("ACEScg",
+		Chromaticity{0.713, 0.293}, Chromaticity{0.165, 0.830}, Chromaticity{0.128, 0.044}, ACESWhite, TransferLinear)
+	// ProPhoto is the ProPhoto (ROMM RGB) color space.
+	ProPhoto = NewColorSpace("ProPhoto",
+		Chromaticity{0.7347, 0.2653}, Chromaticity{0.1596, 0.8404}, Chromaticity{0.0366, 0.0001}, D50, TransferROMM)
+)
+
+// ToXYZ returns the matrix converting linear RGB values of the color space to XYZ.
+func (cs *ColorSpace) ToXYZ() Matrix {
+	return cs.toXYZ
+}
+
+// FromXYZ returns the matrix converting XYZ to linear RGB values of the color space.
+func (cs *ColorSpace) FromXYZ() Matrix {
+	return cs.fromXYZ
+}
+
+// ConversionMatrix returns the matrix converting linear RGB values of the color space to the color space to.
+// The XYZ values are kept, without chromatic adaptation between different white points.
+func (cs *ColorSpace) ConversionMatrix(to *ColorSpace) Matrix {
+	if cs == to {
+		return IdentityMatrix
+	}
+	return to.fromXYZ.Mul(cs.toXYZ)
+}
+
+// Convert converts the linear color c of the color space to the color space to.
+func (cs *ColorSpace) Convert(c RGB, to *ColorSpace) RGB {
+	r, g, b := cs.ConversionMatrix(to).Apply(c.R, c.G, c.B)
+	return RGB{R: r, G: g, B: b}
+}
+
+// XYZ returns the XYZ color of the linear color c of the color space.
+func (cs *ColorSpace) XYZ(c RGB) XYZ {
+	x, y, z := cs.toXYZ.Apply(c.R, c.G, c.B)
+	return XYZ{X: x, Y: y, Z: z}
+}
+
+// RGB returns the linear color of the color space of the XYZ color c.
+func (cs *ColorSpace) RGB(c XYZ) RGB {
+	r, g, b := cs.fromXYZ.Apply(c.X, c.Y, c.Z)
+	return RGB{R: r, G: g, B: b}
+}
+
+// From returns the color c converted to the linear values of the color space, nil for Rec.709.
+// Colors are converted through XYZ relative to D65, with a Bradford chromatic adaptation
+// to the white point of the color space.
+func (cs *ColorSpace) From(c Color) RGB {
+	to := spaceOf(cs)
+
+	var x, y, z float64
+	switch c1 := c.(type) {
+	case RGB:
+		if sameSpace(c1.Space, cs) {
+			return RGB{R: c1.R, G: c1.G, B: c1.B, Space: cs}
+		}
+		x, y, z = spaceOf(c1.Space).toD65.Apply(c1.R, c1.G, c1.B)
+	case RGBA:
+		x, y, z = spaceOf(c1.Space).toD65.Apply(c1.R, c1.G, c1.B)
+	default:
+		x, y, z, _ = c.HDRXYZA()
+	}
+
+	r, g, b := to.fromD65.Apply(x, y, z)
+	return RGB{R: r, G: g, B: b, Space: cs}
+}
+
+// isRec709 returns whether the linear values of cs are the ones of Rec.709 (nil color space).
+func isRec709(cs *ColorSpace) bool {
+	return cs == nil || cs == Rec709 || cs == SRGB
+}
+
+// spaceOf returns cs, Rec709 when nil.
+func spaceOf(cs *ColorSpace) *ColorSpace {
+	if cs == nil {
+		return Rec709
+	}
+	return cs
+}
+
+func sameSpace(cs1, cs2 *ColorSpace) bool {
+	return cs1 == cs2 || isRec709(cs1) && isRec709(cs2)
+}
+
+// toXYZD65 converts linear values of cs to XYZ relative to D65.
+func toXYZD65(cs *ColorSpace, r, g, b float64) (x, y, z float64) {
+	if isRec709(cs) {
+		return colorful.LinearRgbToXyz(r, g, b)
+	}
+	return cs.toD65.Apply(r, g, b)
+}
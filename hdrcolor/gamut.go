@@ -0,0 +1,97 @@
+package hdrcolor
+
+import "math"
+
+// A GamutMapping brings the colors of a color space with negative linear values,
+// out of the gamut of its primaries, back into the gamut.
+// HDR colors are not bounded above so only the negative values are out of gamut.
+type GamutMapping struct {
+	Name string
+	// Map maps the linear values r, g, b of a color whose luminance is y to non-negative values.
+	Map func(r, g, b, y float64) (float64, float64, float64)
+}
+
+// Gamut mappings.
+var (
+	// GamutClip clips the negative values to zero, shifting the hue and luminance of the color.
+	GamutClip = &GamutMapping{"clip", clipGamut}
+	// GamutDesaturate desaturates out-of-gamut colors toward the neutral color of same luminance,
+	// keeping their hue (dominant wavelength) and luminance.
+	GamutDesaturate = &GamutMapping{"desaturate", desaturateGamut}
+	// GamutCompress is the soft-knee compression with a threshold of 0.8 and a limit of 1.2.
+	GamutCompress = softKneeGamut(0.8, 1.2)
+)
+
+// SoftKneeGamut returns a gamut mapping compressing the distance of the colors from the achromatic axis,
+// like the ACES reference gamut compression. Distances below threshold, within [0, 1[, are kept,
+// distances beyond are smoothly compressed so that the distance limit, greater than 1, lands on the gamut boundary.
+// Colors beyond the limit are clipped.
+func SoftKneeGamut(threshold, limit float64) (*GamutMapping, error) {
+	if !(threshold >= 0 && threshold < 1) {
+		return nil, ParameterError("soft-knee threshold")
+	}
+	if !(limit > 1) || math.IsInf(limit, 1) {
+		return nil, ParameterError("soft-knee limit")
+	}
+	return softKneeGamut(threshold, limit), nil
+}
+
+func softKneeGamut(threshold, limit float64) *GamutMapping {
+	const power = 1.2
+	scale := (limit - threshold) / math.Pow(math.Pow((1-threshold)/(limit-threshold), -power)-1, 1/power)
+
+	compress := func(d float64) float64 {
+		if d < threshold {
+			return d
+		}
+		n := (d - threshold) / scale
+		return threshold + scale*n/math.Pow(1+math.Pow(n, power), 1/power)
+	}
+
+	return &GamutMapping{"soft-knee", func(r, g, b, _ float64) (float64, float64, float64) {
+		ach := math.Max(r, math.Max(g, b))
+		if ach <= 0 {
+			return 0, 0, 0
+		}
+
+		r = ach - compress((ach-r)/ach)*ach
+		g = ach - compress((ach-g)/ach)*ach
+		b = ach - compress((ach-b)/ach)*ach
+		return clipGamut(r, g, b, 0)
+	}}
+}
+
+func clipGamut(r, g, b, _ float64) (float64, float64, float64) {
+	return math.Max(r, 0), math.Max(g, 0), math.Max(b, 0)
+}
+
+func desaturateGamut(r, g, b, y float64) (float64, float64, float64) {
+	min := math.Min(r, math.Min(g, b))
+	if min >= 0 {
+		return r, g, b
+	}
+	if y <= 0 {
+		return 0, 0, 0
+	}
+
+	// Mix with the neutral color (y, y, y) until the smallest value reaches zero.
+	s := y / (y - min)
+	r = y + s*(r-y)
+	g = y + s*(g-y)
+	b = y + s*(b-y)
+	return clipGamut(r, g, b, 0) // Rounding
+}
+
+// MapGamut maps the linear color c of the color space into its gamut with gm.
+func (cs *ColorSpace) MapGamut(c RGB, gm *GamutMapping) RGB {
+	y := cs.toXYZ[1][0]*c.R + cs.toXYZ[1][1]*c.G + cs.toXYZ[1][2]*c.B
+	r, g, b := gm.Map(c.R, c.G, c.B, y)
+	return RGB{R: r, G: g, B: b, Space: cs}
+}
+
+// A ParameterError reports an invalid parameter.
+type ParameterError string
+
+func (e ParameterError) Error() string {
+	return "hdrcolor: invalid parameter: " + string(e)
+}
@@ -0,0 +1,192 @@
+package hdrcolor
+
+import (
+	"image/color"
+	"math"
+)
+
+// ReferenceLuminance is the luminance in cd/m² of the relative luminance Y = 1,
+// used by the absolute color-spaces ICtCp and Jzazbz.
+var ReferenceLuminance = 100.0
+
+// Models for the absolute color types.
+var (
+	ICtCpModel  color.Model = color.ModelFunc(ictcpModel)
+	JzazbzModel color.Model = color.ModelFunc(jzazbzModel)
+)
+
+//===============//
+// ICtCp         //
+//===============//
+
+var (
+	// XYZ (D65) to the LMS cone responses of ICtCp (ITU-R BT.2100).
+	ictcpLMS = Matrix{
+		{1688.0 / 4096, 2146.0 / 4096, 262.0 / 4096},
+		{683.0 / 4096, 2951.0 / 4096, 462.0 / 4096},
+		{99.0 / 4096, 309.0 / 4096, 3688.0 / 4096},
+	}.Mul(Rec2020.FromXYZ())
+	lmsICtCp = Matrix{
+		{0.5, 0.5, 0},
+		{6610.0 / 4096, -13613.0 / 4096, 7003.0 / 4096},
+		{17933.0 / 4096, -17390.0 / 4096, -543.0 / 4096},
+	}
+	ictcpXYZ   = ictcpLMS.Inverse()
+	ictcpLMSPQ = lmsICtCp.Inverse()
+)
+
+// ICtCp represents a color in the ICtCp color-space of ITU-R BT.2100 with the PQ transfer function.
+// The luminance Y = 1 is ReferenceLuminance cd/m².
+type ICtCp struct {
+	I, Ct, Cp float64
+}
+
+// RGBA returns the alpha-premultiplied red, green, blue and alpha values
+// for the color. Each value ranges within [0, 0xffff], but is represented
+// by a uint32 so that multiplying by a blend factor up to 0xffff will not
+// overflow.
+func (c ICtCp) RGBA() (r, g, b, a uint32) {
+	return rgba(c)
+}
+
+// HDRRGBA returns the red, green, blue and alpha values
+// for the HDR color.
+func (c ICtCp) HDRRGBA() (r, g, b, a float64) {
+	return hdrRGBA(c)
+}
+
+// HDRXYZA returns the x, y, z and alpha values
+// for the HDR color.
+func (c ICtCp) HDRXYZA() (x, y, z, a float64) {
+	l, m, s := ictcpLMSPQ.Apply(c.I, c.Ct, c.Cp)
+
+	scale := 10000 / ReferenceLuminance
+	l, m, s = pqInv(l, pqM2)*scale, pqInv(m, pqM2)*scale, pqInv(s, pqM2)*scale
+
+	x, y, z = ictcpXYZ.Apply(l, m, s)
+	return x, y, z, 1
+}
+
+// DeltaE returns the ΔE ITP color difference (ITU-R BT.2124) between c and o,
+// 1 being a just noticeable difference.
+func (c ICtCp) DeltaE(o ICtCp) float64 {
+	return 720 * math.Sqrt(sq(c.I-o.I)+sq(0.5*(c.Ct-o.Ct))+sq(c.Cp-o.Cp))
+}
+
+func ictcpModel(c color.Color) color.Color {
+	if _, ok := c.(ICtCp); ok {
+		// Already ICtCp
+		return c
+	}
+
+	x, y, z, _ := xyza(c)
+	l, m, s := ictcpLMS.Apply(x, y, z)
+
+	scale := ReferenceLuminance / 10000
+	l, m, s = pq(l*scale, pqM2), pq(m*scale, pqM2), pq(s*scale, pqM2)
+
+	i, ct, cp := lmsICtCp.Apply(l, m, s)
+	return ICtCp{I: i, Ct: ct, Cp: cp}
+}
+
+//===============//
+// Jzazbz        //
+//===============//
+
+// Jzazbz constants (Safdar et al. 2017)
+const (
+	jzB  = 1.15
+	jzG  = 0.66
+	jzD  = -0.56
+	jzD0 = 1.6295499532821566e-11
+	jzP  = 1.7 * 2523.0 / 32
+)
+
+var (
+	jzLMS = Matrix{
+		{0.41478972, 0.579999, 0.0146480},
+		{-0.2015100, 1.120649, 0.0531008},
+		{-0.0166008, 0.264800, 0.6684799},
+	}
+	jzIab = Matrix{
+		{0.5, 0.5, 0},
+		{3.524000, -4.066708, 0.542708},
+		{0.199076, 1.096799, -1.295875},
+	}
+	jzXYZ    = jzLMS.Inverse()
+	jzLMSmod = jzIab.Inverse()
+)
+
+// Jzazbz represents a color in the Jzazbz color-space designed for HDR and wide gamut,
+// with perceptually uniform lightness and hue. The luminance Y = 1 is ReferenceLuminance cd/m².
+type Jzazbz struct {
+	Jz, Az, Bz float64
+}
+
+// RGBA returns the alpha-premultiplied red, green, blue and alpha values
+// for the color. Each value ranges within [0, 0xffff], but is represented
+// by a uint32 so that multiplying by a blend factor up to 0xffff will not
+// overflow.
+func (c Jzazbz) RGBA() (r, g, b, a uint32) {
+	return rgba(c)
+}
+
+// HDRRGBA returns the red, green, blue and alpha values
+// for the HDR color.
+func (c Jzazbz) HDRRGBA() (r, g, b, a float64) {
+	return hdrRGBA(c)
+}
+
+// HDRXYZA returns the x, y, z and alpha values
+// for the HDR color.
+func (c Jzazbz) HDRXYZA() (x, y, z, a float64) {
+	jz := c.Jz + jzD0
+	iz := jz / (1 + jzD - jzD*jz)
+
+	l, m, s := jzLMSmod.Apply(iz, c.Az, c.Bz)
+	l, m, s = pqInv(l, jzP)*10000, pqInv(m, jzP)*10000, pqInv(s, jzP)*10000
+
+	xp, yp, zp := jzXYZ.Apply(l, m, s)
+	x = (xp + (jzB-1)*zp) / jzB
+	y = (yp + (jzG-1)*x) / jzG
+
+	scale := 1 / ReferenceLuminance
+	return x * scale, y * scale, zp * scale, 1
+}
+
+// Chroma returns the chroma Cz of the color.
+func (c Jzazbz) Chroma() float64 {
+	return math.Hypot(c.Az, c.Bz)
+}
+
+// Hue returns the hue angle hz of the color in radians.
+func (c Jzazbz) Hue() float64 {
+	return math.Atan2(c.Bz, c.Az)
+}
+
+// DeltaE returns the ΔEz color difference between c and o.
+func (c Jzazbz) DeltaE(o Jzazbz) float64 {
+	c1, c2 := c.Chroma(), o.Chroma()
+	dh := 2 * math.Sqrt(c1*c2) * math.Sin((c.Hue()-o.Hue())/2)
+	return math.Sqrt(sq(c.Jz-o.Jz) + sq(c1-c2) + sq(dh))
+}
+
+func jzazbzModel(c color.Color) color.Color {
+	if _, ok := c.(Jzazbz); ok {
+		// Already Jzazbz
+		return c
+	}
+
+	x, y, z, _ := xyza(c)
+	x, y, z = x*ReferenceLuminance, y*ReferenceLuminance, z*ReferenceLuminance
+
+	xp := jzB*x - (jzB-1)*z
+	yp := jzG*y - (jzG-1)*x
+
+	l, m, s := jzLMS.Apply(xp, yp, z)
+	l, m, s = pq(l/10000, jzP), pq(m/10000, jzP), pq(s/10000, jzP)
+
+	iz, az, bz := jzIab.Apply(l, m, s)
+	jz := (1+jzD)*iz/(1+jzD*iz) - jzD0
+	return Jzazbz{Jz: jz, Az: az, Bz: bz}
+}
@@ -0,0 +1,194 @@
+package hdrcolor
+
+import (
+	"image/color"
+	"math"
+
+	colorful "github.com/lucasb-eyer/go-colorful"
+)
+
+// CIE constants
+const (
+	labEpsilon = 216.0 / 24389
+	labKappa   = 24389.0 / 27
+)
+
+// Lab represents a color in the CIE 1976 L*a*b* color-space,
+// relative to the D65 white point of luminance Y = 1 (L* = 100).
+// HDR colors brighter than the white have a L* greater than 100.
+type Lab struct {
+	L, A, B float64
+}
+
+// RGBA returns the alpha-premultiplied red, green, blue and alpha values
+// for the color. Each value ranges within [0, 0xffff], but is represented
+// by a uint32 so that multiplying by a blend factor up to 0xffff will not
+// overflow.
+func (c Lab) RGBA() (r, g, b, a uint32) {
+	return rgba(c)
+}
+
+// HDRRGBA returns the red, green, blue and alpha values
+// for the HDR color.
+func (c Lab) HDRRGBA() (r, g, b, a float64) {
+	return hdrRGBA(c)
+}
+
+// HDRXYZA returns the x, y, z and alpha values
+// for the HDR color.
+func (c Lab) HDRXYZA() (x, y, z, a float64) {
+	wx, wy, wz := D65.XYZ()
+
+	fy := (c.L + 16) / 116
+	fx := fy + c.A/500
+	fz := fy - c.B/200
+
+	yr := c.L / labKappa
+	if c.L > labKappa*labEpsilon {
+		yr = fy * fy * fy
+	}
+
+	return labFinv(fx) * wx, yr * wy, labFinv(fz) * wz, 1
+}
+
+// DeltaE returns the CIE76 color difference (ΔE*ab) between c and o.
+func (c Lab) DeltaE(o Lab) float64 {
+	return math.Sqrt(sq(c.L-o.L) + sq(c.A-o.A) + sq(c.B-o.B))
+}
+
+func labF(t float64) float64 {
+	if t > labEpsilon {
+		return math.Cbrt(t)
+	}
+	return (labKappa*t + 16) / 116
+}
+
+func labFinv(f float64) float64 {
+	if f3 := f * f * f; f3 > labEpsilon {
+		return f3
+	}
+	return (116*f - 16) / labKappa
+}
+
+// Luv represents a color in the CIE 1976 L*u*v* color-space,
+// relative to the D65 white point of luminance Y = 1 (L* = 100).
+type Luv struct {
+	L, U, V float64
+}
+
+// RGBA returns the alpha-premultiplied red, green, blue and alpha values
+// for the color. Each value ranges within [0, 0xffff], but is represented
+// by a uint32 so that multiplying by a blend factor up to 0xffff will not
+// overflow.
+func (c Luv) RGBA() (r, g, b, a uint32) {
+	return rgba(c)
+}
+
+// HDRRGBA returns the red, green, blue and alpha values
+// for the HDR color.
+func (c Luv) HDRRGBA() (r, g, b, a float64) {
+	return hdrRGBA(c)
+}
+
+// HDRXYZA returns the x, y, z and alpha values
+// for the HDR color.
+func (c Luv) HDRXYZA() (x, y, z, a float64) {
+	if c.L <= 0 {
+		return 0, 0, 0, 1
+	}
+	un, vn := uv(D65.XYZ())
+
+	up := c.U/(13*c.L) + un
+	vp := c.V/(13*c.L) + vn
+
+	y = c.L / labKappa
+	if c.L > labKappa*labEpsilon {
+		fy := (c.L + 16) / 116
+		y = fy * fy * fy
+	}
+
+	x = y * 9 * up / (4 * vp)
+	z = y * (12 - 3*up - 20*vp) / (4 * vp)
+	return x, y, z, 1
+}
+
+// DeltaE returns the CIE76 color difference (ΔE*uv) between c and o.
+func (c Luv) DeltaE(o Luv) float64 {
+	return math.Sqrt(sq(c.L-o.L) + sq(c.U-o.U) + sq(c.V-o.V))
+}
+
+// uv returns the CIE 1976 u'v' chromaticity of the XYZ values.
+func uv(x, y, z float64) (u, v float64) {
+	d := x + 15*y + 3*z
+	if d == 0 {
+		return 0, 0
+	}
+	return 4 * x / d, 9 * y / d
+}
+
+// Models for the CIE color types.
+var (
+	LabModel color.Model = color.ModelFunc(labModel)
+	LuvModel color.Model = color.ModelFunc(luvModel)
+)
+
+// xyza returns the HDR XYZ values of any color.
+func xyza(c color.Color) (x, y, z, a float64) {
+	if hdrc, ok := c.(Color); ok {
+		return hdrc.HDRXYZA()
+	}
+	xyz := xyzModel(c).(XYZ)
+	return xyz.X, xyz.Y, xyz.Z, 1
+}
+
+func labModel(c color.Color) color.Color {
+	if _, ok := c.(Lab); ok {
+		// Already Lab
+		return c
+	}
+
+	x, y, z, _ := xyza(c)
+	wx, wy, wz := D65.XYZ()
+	fx, fy, fz := labF(x/wx), labF(y/wy), labF(z/wz)
+
+	return Lab{L: 116*fy - 16, A: 500 * (fx - fy), B: 200 * (fy - fz)}
+}
+
+func luvModel(c color.Color) color.Color {
+	if _, ok := c.(Luv); ok {
+		// Already Luv
+		return c
+	}
+
+	x, y, z, _ := xyza(c)
+	wx, wy, wz := D65.XYZ()
+
+	l := labKappa * y / wy
+	if y/wy > labEpsilon {
+		l = 116*math.Cbrt(y/wy) - 16
+	}
+	if l <= 0 {
+		return Luv{L: l}
+	}
+
+	up, vp := uv(x, y, z)
+	un, vn := uv(wx, wy, wz)
+	return Luv{L: l, U: 13 * l * (up - un), V: 13 * l * (vp - vn)}
+}
+
+// rgba converts an HDR color to LDR values like XYZ.RGBA.
+func rgba(c Color) (r, g, b, a uint32) {
+	x, y, z, _ := c.HDRXYZA()
+	return XYZ{X: x, Y: y, Z: z}.RGBA()
+}
+
+// hdrRGBA converts an HDR color to linear Rec.709 values from its XYZ values.
+func hdrRGBA(c Color) (r, g, b, a float64) {
+	x, y, z, a := c.HDRXYZA()
+	r, g, b = colorful.XyzToLinearRgb(x, y, z)
+	return
+}
+
+func sq(v float64) float64 {
+	return v * v
+}
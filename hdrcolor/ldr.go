@@ -0,0 +1,68 @@
+package hdrcolor
+
+import (
+	"image/color"
+	"math"
+	"sync"
+)
+
+// LDR colors (color.Color) hold 16-bit sRGB encoded values whereas HDR colors hold linear values
+// where 1 is the reference white.
+
+var (
+	ldrOnce  sync.Once
+	ldrTable []float64
+)
+
+// FromLDR returns the linear value within [0, 1] of an sRGB encoded 16-bit value.
+func FromLDR(v uint32) float64 {
+	ldrOnce.Do(func() {
+		ldrTable = make([]float64, 0x10000)
+		for i := range ldrTable {
+			ldrTable[i] = TransferSRGB.Decode(float64(i) / 0xFFFF)
+		}
+	})
+	return ldrTable[v&0xFFFF]
+}
+
+// ToLDR returns the sRGB encoded 16-bit value of a linear value, clamped within [0, 1].
+func ToLDR(v float64) uint32 {
+	if !(v > 0) { // Also NaN
+		return 0
+	}
+	if v >= 1 {
+		return 0xFFFF
+	}
+	return uint32(TransferSRGB.Encode(v)*0xFFFF + 0.5)
+}
+
+// ldrRGBA returns the alpha-premultiplied sRGB encoded values of linear non-premultiplied values.
+func ldrRGBA(r, g, b, a float64) (uint32, uint32, uint32, uint32) {
+	a = math.Max(0, math.Min(a, 1))
+	if a == 1 {
+		return ToLDR(r), ToLDR(g), ToLDR(b), 0xFFFF
+	}
+
+	aa := uint32(a*0xFFFF + 0.5)
+	return ToLDR(r) * aa / 0xFFFF, ToLDR(g) * aa / 0xFFFF, ToLDR(b) * aa / 0xFFFF, aa
+}
+
+// hdrRGB returns the linear values of an LDR color composited over black, like color.Color conversions to opaque models.
+func hdrRGB(c color.Color) (r, g, b float64) {
+	rr, gg, bb, _ := c.RGBA()
+	return FromLDR(rr), FromLDR(gg), FromLDR(bb)
+}
+
+// hdrRGBAFromLDR returns the linear non-premultiplied values of an LDR color.
+func hdrRGBAFromLDR(c color.Color) (r, g, b, a float64) {
+	rr, gg, bb, aa := c.RGBA()
+	if aa == 0 {
+		return 0, 0, 0, 0
+	}
+	if aa != 0xFFFF {
+		rr = rr * 0xFFFF / aa
+		gg = gg * 0xFFFF / aa
+		bb = bb * 0xFFFF / aa
+	}
+	return FromLDR(rr), FromLDR(gg), FromLDR(bb), float64(aa) / 0xFFFF
+}
@@ -0,0 +1,49 @@
+package hdrcolor
+
+// A Matrix is a 3x3 matrix converting color triplets (row-major order).
+type Matrix [3][3]float64
+
+// IdentityMatrix leaves colors unchanged.
+var IdentityMatrix = Matrix{
+	{1, 0, 0},
+	{0, 1, 0},
+	{0, 0, 1},
+}
+
+// Apply returns the product of m with the column vector (a, b, c).
+func (m Matrix) Apply(a, b, c float64) (float64, float64, float64) {
+	return m[0][0]*a + m[0][1]*b + m[0][2]*c,
+		m[1][0]*a + m[1][1]*b + m[1][2]*c,
+		m[2][0]*a + m[2][1]*b + m[2][2]*c
+}
+
+// Mul returns the product m × n, that applies n then m.
+func (m Matrix) Mul(n Matrix) Matrix {
+	var p Matrix
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			p[i][j] = m[i][0]*n[0][j] + m[i][1]*n[1][j] + m[i][2]*n[2][j]
+		}
+	}
+	return p
+}
+
+// Inverse returns the inverse of m. A singular matrix returns the zero matrix.
+func (m Matrix) Inverse() Matrix {
+	// Cofactors
+	c00 := m[1][1]*m[2][2] - m[1][2]*m[2][1]
+	c01 := m[1][2]*m[2][0] - m[1][0]*m[2][2]
+	c02 := m[1][0]*m[2][1] - m[1][1]*m[2][0]
+
+	det := m[0][0]*c00 + m[0][1]*c01 + m[0][2]*c02
+	if det == 0 {
+		return Matrix{}
+	}
+	inv := 1 / det
+
+	return Matrix{
+		{c00 * inv, (m[0][2]*m[2][1] - m[0][1]*m[2][2]) * inv, (m[0][1]*m[1][2] - m[0][2]*m[1][1]) * inv},
+		{c01 * inv, (m[0][0]*m[2][2] - m[0][2]*m[2][0]) * inv, (m[0][2]*m[1][0] - m[0][0]*m[1][2]) * inv},
+		{c02 * inv, (m[0][1]*m[2][0] - m[0][0]*m[2][1]) * inv, (m[0][0]*m[1][1] - m[0][1]*m[1][0]) * inv},
+	}
+}
@@ -0,0 +1,209 @@
+package hdrcolor
+
+import "math"
+
+// A Transfer is the transfer function of a color space,
+// converting linear values to their non-linear encoding and back.
+// Negative values are mirrored.
+type Transfer struct {
+	Name string
+	// Encode converts a linear value to its non-linear encoding (OETF or inverse EOTF).
+	Encode func(v float64) float64
+	// Decode converts a non-linear value to its linear value.
+	Decode func(v float64) float64
+}
+
+// Built-in transfer functions.
+var (
+	// TransferLinear leaves values unchanged.
+	TransferLinear = &Transfer{
+		Name:   "linear",
+		Encode: func(v float64) float64 { return v },
+		Decode: func(v float64) float64 { return v },
+	}
+	// TransferSRGB is the sRGB (IEC 61966-2-1) transfer function, also used by Display P3.
+	TransferSRGB = &Transfer{
+		Name: "sRGB",
+		Encode: mirrored(func(v float64) float64 {
+			if v <= 0.0031308 {
+				return 12.92 * v
+			}
+			return 1.055*math.Pow(v, 1/2.4) - 0.055
+		}),
+		Decode: mirrored(func(v float64) float64 {
+			if v <= 0.04045 {
+				return v / 12.92
+			}
+			return math.Pow((v+0.055)/1.055, 2.4)
+		}),
+	}
+	// TransferRec709 is the ITU-R BT.709 (and BT.2020) camera transfer function (OETF).
+	TransferRec709 = &Transfer{
+		Name: "Rec.709",
+		Encode: mirrored(func(v float64) float64 {
+			if v < 0.018 {
+				return 4.5 * v
+			}
+			return 1.099*math.Pow(v, 0.45) - 0.099
+		}),
+		Decode: mirrored(func(v float64) float64 {
+			if v < 0.081 {
+				return v / 4.5
+			}
+			return math.Pow((v+0.099)/1.099, 1/0.45)
+		}),
+	}
+	// TransferPQ is the SMPTE ST 2084 (PQ) inverse EOTF of HDR10, the linear value 1 being 10000 cd/m².
+	TransferPQ = &Transfer{
+		Name:   "PQ",
+		Encode: func(v float64) float64 { return pq(v, pqM2) },
+		Decode: func(v float64) float64 { return pqInv(v, pqM2) },
+	}
+	// TransferHLG is the ARIB STD-B67 (HLG) OETF of ITU-R BT.2100 for scene linear values within [0, 1].
+	TransferHLG = &Transfer{
+		Name:   "HLG",
+		Encode: mirrored(HLGOETF),
+		Decode: mirrored(HLGInverseOETF),
+	}
+	// TransferDCI is the 2.6 gamma of DCI-P3.
+	TransferDCI = GammaTransfer("gamma 2.6", 2.6)
+	// TransferROMM is the ROMM RGB (ProPhoto) transfer function, a 1.8 gamma with a linear segment.
+	TransferROMM = &Transfer{
+		Name: "ROMM",
+		Encode: mirrored(func(v float64) float64 {
+			if v < 1.0/512 {
+				return 16 * v
+			}
+			return math.Pow(v, 1/1.8)
+		}),
+		Decode: mirrored(func(v float64) float64 {
+			if v < 16.0/512 {
+				return v / 16
+			}
+			return math.Pow(v, 1.8)
+		}),
+	}
+)
+
+// GammaTransfer returns a pure power transfer function of the given gamma.
+func GammaTransfer(name string, gamma float64) *Transfer {
+	return &Transfer{
+		Name: name,
+		Encode: mirrored(func(v float64) float64 {
+			return math.Pow(v, 1/gamma)
+		}),
+		Decode: mirrored(func(v float64) float64 {
+			return math.Pow(v, gamma)
+		}),
+	}
+}
+
+// mirrored extends f to negative values.
+func mirrored(f func(v float64) float64) func(v float64) float64 {
+	return func(v float64) float64 {
+		if v < 0 {
+			return -f(-v)
+		}
+		return f(v)
+	}
+}
+
+// PQ (SMPTE ST 2084) constants
+const (
+	pqM1 = 2610.0 / 16384
+	pqM2 = 2523.0 / 4096 * 128
+	pqC1 = 3424.0 / 4096
+	pqC2 = 2413.0 / 4096 * 32
+	pqC3 = 2392.0 / 4096 * 32
+)
+
+// pq converts a linear luminance normalized to 10000 cd/m² to its non-linear PQ value
+// with the exponent m2. Negative values are mirrored.
+func pq(v, m2 float64) float64 {
+	if v < 0 {
+		return -pq(-v, m2)
+	}
+	p := math.Pow(v, pqM1)
+	return math.Pow((pqC1+pqC2*p)/(1+pqC3*p), m2)
+}
+
+// pqInv is the inverse of pq.
+func pqInv(v, m2 float64) float64 {
+	if v < 0 {
+		return -pqInv(-v, m2)
+	}
+	p := math.Pow(v, 1/m2)
+	return math.Pow(math.Max(p-pqC1, 0)/(pqC2-pqC3*p), 1/pqM1)
+}
+
+// PQEncode is the PQ inverse EOTF converting a luminance in cd/m² (up to 10000) to a non-linear signal within [0, 1].
+func PQEncode(nits float64) float64 {
+	return pq(math.Max(nits, 0)/10000, pqM2)
+}
+
+// PQDecode is the PQ EOTF converting a non-linear signal within [0, 1] to a luminance in cd/m².
+func PQDecode(v float64) float64 {
+	return pqInv(math.Max(v, 0), pqM2) * 10000
+}
+
+// HLG constants
+const (
+	hlgA = 0.17883277
+	hlgB = 1 - 4*hlgA
+)
+
+var hlgC = 0.5 - hlgA*math.Log(4*hlgA)
+
+// HLGOETF converts a normalized scene linear value within [0, 1] to a non-linear HLG signal within [0, 1].
+func HLGOETF(e float64) float64 {
+	if e <= 0 {
+		return 0
+	}
+	if e <= 1.0/12 {
+		return math.Sqrt(3 * e)
+	}
+	return hlgA*math.Log(12*e-hlgB) + hlgC
+}
+
+// HLGInverseOETF converts a non-linear HLG signal within [0, 1] to a normalized scene linear value.
+func HLGInverseOETF(v float64) float64 {
+	if v <= 0 {
+		return 0
+	}
+	if v <= 0.5 {
+		return v * v / 3
+	}
+	return (math.Exp((v-hlgC)/hlgA) + hlgB) / 12
+}
+
+// HLGGamma returns the system gamma of the HLG OOTF for a display of the given peak luminance in cd/m².
+func HLGGamma(peak float64) float64 {
+	return 1.2 + 0.42*math.Log10(peak/1000)
+}
+
+// HLGEOTF converts non-linear HLG Rec.2020 signals to the displayed linear light in cd/m²
+// on a display of the given peak luminance (inverse OETF followed by the OOTF).
+func HLGEOTF(r, g, b, peak float64) (float64, float64, float64) {
+	r, g, b = HLGInverseOETF(r), HLGInverseOETF(g), HLGInverseOETF(b)
+
+	ys := 0.2627*r + 0.6780*g + 0.0593*b
+	if ys <= 0 {
+		return 0, 0, 0
+	}
+	s := peak * math.Pow(ys, HLGGamma(peak)-1)
+	return r * s, g * s, b * s
+}
+
+// HLGInverseEOTF converts the displayed linear Rec.2020 light in cd/m² on a display of the given peak luminance
+// to non-linear HLG signals (inverse OOTF followed by the OETF).
+func HLGInverseEOTF(r, g, b, peak float64) (float64, float64, float64) {
+	r, g, b = math.Max(r/peak, 0), math.Max(g/peak, 0), math.Max(b/peak, 0)
+
+	yd := 0.2627*r + 0.6780*g + 0.0593*b
+	if yd <= 0 {
+		return 0, 0, 0
+	}
+	gamma := HLGGamma(peak)
+	s := math.Pow(yd, (1-gamma)/gamma)
+	return HLGOETF(r * s), HLGOETF(g * s), HLGOETF(b * s)
+}
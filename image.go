@@ -4,6 +4,7 @@ import (
 	"image"
 	"image/color"
 
+	"github.com/Xyzyx101/hdr/format"
 	"github.com/Xyzyx101/hdr/hdrcolor"
 )
 
@@ -31,13 +32,15 @@ type RGB struct {
 	Stride int
 	// Rect is the image's bounds.
 	Rect image.Rectangle
+	// Space is the color space of the linear pixels, nil for Rec.709 (see ColorSpace).
+	Space *hdrcolor.ColorSpace
 }
 
 // NewRGB returns a new HDR RGB image with the given bounds.
 func NewRGB(r image.Rectangle) *RGB {
 	w, h := r.Dx(), r.Dy()
 	buf := make([]float32, 3*w*h)
-	return &RGB{buf, 3 * w, r}
+	return &RGB{Pix: buf, Stride: 3 * w, Rect: r}
 }
 
 // ColorModel implements Image.
@@ -68,9 +71,10 @@ func (p *RGB) RGBAt(x, y int) hdrcolor.RGB {
 	}
 	i := p.PixOffset(x, y)
 	return hdrcolor.RGB{
-		R: float64(p.Pix[i+0]),
-		G: float64(p.Pix[i+1]),
-		B: float64(p.Pix[i+2]),
+		R:     float64(p.Pix[i+0]),
+		G:     float64(p.Pix[i+1]),
+		B:     float64(p.Pix[i+2]),
+		Space: p.Space,
 	}
 }
 
@@ -80,20 +84,21 @@ func (p *RGB) PixOffset(x, y int) int {
 	return (y-p.Rect.Min.Y)*p.Stride + (x-p.Rect.Min.X)*3
 }
 
-// Set implements Image.
+// Set implements Image. The color is converted to the color space of the image.
 func (p *RGB) Set(x, y int, c color.Color) {
 	if !(image.Point{x, y}.In(p.Rect)) {
 		return
 	}
 	i := p.PixOffset(x, y)
 
-	c1 := hdrcolor.RGBModel.Convert(c).(hdrcolor.RGB)
+	c1 := p.Space.From(hdrcolor.RGBModel.Convert(c).(hdrcolor.RGB))
 	p.Pix[i+0] = float32(c1.R)
 	p.Pix[i+1] = float32(c1.G)
 	p.Pix[i+2] = float32(c1.B)
 }
 
 // SetRGB applies the given RGB color at this coordinate.
+// Its values are stored as they are, in the color space of the image.
 func (p *RGB) SetRGB(x, y int, c hdrcolor.RGB) {
 	if !(image.Point{x, y}.In(p.Rect)) {
 		return
@@ -119,6 +124,7 @@ func (p *RGB) SubImage(r image.Rectangle) Image {
 		Pix:    p.Pix[i:],
 		Stride: p.Stride,
 		Rect:   r,
+		Space:  p.Space,
 	}
 }
 
@@ -131,13 +137,15 @@ type RGB64 struct {
 	Stride int
 	// Rect is the image's bounds.
 	Rect image.Rectangle
+	// Space is the color space of the linear pixels, nil for Rec.709 (see ColorSpace).
+	Space *hdrcolor.ColorSpace
 }
 
 // NewRGB64 returns a new HDR RGB image with the given bounds.
 func NewRGB64(r image.Rectangle) *RGB64 {
 	w, h := r.Dx(), r.Dy()
 	buf := make([]float64, 3*w*h)
-	return &RGB64{buf, 3 * w, r}
+	return &RGB64{Pix: buf, Stride: 3 * w, Rect: r}
 }
 
 // ColorModel implements Image.
@@ -167,7 +175,7 @@ func (p *RGB64) RGBAt(x, y int) hdrcolor.RGB {
 		return hdrcolor.RGB{}
 	}
 	i := p.PixOffset(x, y)
-	return hdrcolor.RGB{R: p.Pix[i+0], G: p.Pix[i+1], B: p.Pix[i+2]}
+	return hdrcolor.RGB{R: p.Pix[i+0], G: p.Pix[i+1], B: p.Pix[i+2], Space: p.Space}
 }
 
 // PixOffset returns the index of the first element of Pix that corresponds to
@@ -176,20 +184,21 @@ func (p *RGB64) PixOffset(x, y int) int {
 	return (y-p.Rect.Min.Y)*p.Stride + (x-p.Rect.Min.X)*3
 }
 
-// Set implements Image.
+// Set implements Image. The color is converted to the color space of the image.
 func (p *RGB64) Set(x, y int, c color.Color) {
 	if !(image.Point{x, y}.In(p.Rect)) {
 		return
 	}
 	i := p.PixOffset(x, y)
 
-	c1 := hdrcolor.RGBModel.Convert(c).(hdrcolor.RGB)
+	c1 := p.Space.From(hdrcolor.RGBModel.Convert(c).(hdrcolor.RGB))
 	p.Pix[i+0] = c1.R
 	p.Pix[i+1] = c1.G
 	p.Pix[i+2] = c1.B
 }
 
 // SetRGB applies the given RGB color at this coordinate.
+// Its values are stored as they are, in the color space of the image.
 func (p *RGB64) SetRGB(x, y int, c hdrcolor.RGB) {
 	if !(image.Point{x, y}.In(p.Rect)) {
 		return
@@ -379,5 +388,631 @@ func (p *RGB64) SubImage(r image.Rectangle) Image {
 		Pix:    p.Pix[i:],
 		Stride: p.Stride,
 		Rect:   r,
+		Space:  p.Space,
+	}
+}
+
+//===============//
+// RGBA          //
+//===============//
+
+// RGBA is an in-memory 32 bits floating points image whose At method returns hdrcolor.RGBA values.
+// The colors are not alpha-premultiplied.
+type RGBA struct {
+	// Pix holds the image's pixels, in R, G, B, A order. The pixel at
+	// (x, y) starts at Pix[(y-Rect.Min.Y)*Stride + (x-Rect.Min.X)*4].
+	Pix []float32
+	// Stride is the Pix stride between vertically adjacent pixels.
+	Stride int
+	// Rect is the image's bounds.
+	Rect image.Rectangle
+	// Space is the color space of the linear pixels, nil for Rec.709 (see ColorSpace).
+	Space *hdrcolor.ColorSpace
+}
+
+// NewRGBA returns a new HDR RGBA image with the given bounds.
+func NewRGBA(r image.Rectangle) *RGBA {
+	w, h := r.Dx(), r.Dy()
+	buf := make([]float32, 4*w*h)
+	return &RGBA{Pix: buf, Stride: 4 * w, Rect: r}
+}
+
+// ColorModel implements Image.
+func (p *RGBA) ColorModel() color.Model { return hdrcolor.RGBAModel }
+
+// Bounds implements Image.
+func (p *RGBA) Bounds() image.Rectangle { return p.Rect }
+
+// Size implements Image.
+func (p *RGBA) Size() int {
+	return p.Bounds().Dx() * p.Bounds().Dy()
+}
+
+// At implements Image.
+func (p *RGBA) At(x, y int) color.Color {
+	return p.RGBAAt(x, y)
+}
+
+// HDRAt implements Image.
+func (p *RGBA) HDRAt(x, y int) hdrcolor.Color {
+	return p.RGBAAt(x, y)
+}
+
+// RGBAAt returns the RGBA color at this coordinate.
+func (p *RGBA) RGBAAt(x, y int) hdrcolor.RGBA {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return hdrcolor.RGBA{}
+	}
+	i := p.PixOffset(x, y)
+	return hdrcolor.RGBA{
+		R:     float64(p.Pix[i+0]),
+		G:     float64(p.Pix[i+1]),
+		B:     float64(p.Pix[i+2]),
+		A:     float64(p.Pix[i+3]),
+		Space: p.Space,
+	}
+}
+
+// PixOffset returns the index of the first element of Pix that corresponds to
+// the pixel at (x, y).
+func (p *RGBA) PixOffset(x, y int) int {
+	return (y-p.Rect.Min.Y)*p.Stride + (x-p.Rect.Min.X)*4
+}
+
+// Set implements Image. The color is converted to the color space of the image.
+func (p *RGBA) Set(x, y int, c color.Color) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	p.SetRGBA(x, y, rgbaIn(p.Space, c))
+}
+
+// SetRGBA applies the given RGBA color at this coordinate.
+// Its values are stored as they are, in the color space of the image.
+func (p *RGBA) SetRGBA(x, y int, c hdrcolor.RGBA) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	i := p.PixOffset(x, y)
+	p.Pix[i+0] = float32(c.R)
+	p.Pix[i+1] = float32(c.G)
+	p.Pix[i+2] = float32(c.B)
+	p.Pix[i+3] = float32(c.A)
+}
+
+// SubImage returns an image representing the portion of the image p visible
+// through r. The returned value shares pixels with the original image.
+func (p *RGBA) SubImage(r image.Rectangle) Image {
+	r = r.Intersect(p.Rect)
+	// If r1 and r2 are Rectangles, r1.Intersect(r2) is not guaranteed to be inside
+	// either r1 or r2 if the intersection is empty. Without explicitly checking for
+	// this, the Pix[i:] expression below can panic.
+	if r.Empty() {
+		return &RGBA{}
+	}
+	i := p.PixOffset(r.Min.X, r.Min.Y)
+	return &RGBA{
+		Pix:    p.Pix[i:],
+		Stride: p.Stride,
+		Rect:   r,
+		Space:  p.Space,
+	}
+}
+
+//===============//
+// XYZA          //
+//===============//
+
+// XYZA is an in-memory 32 bits floating points image whose At method returns hdrcolor.XYZA values.
+// The colors are not alpha-premultiplied.
+type XYZA struct {
+	// Pix holds the image's pixels, in X, Y, Z, A order. The pixel at
+	// (x, y) starts at Pix[(y-Rect.Min.Y)*Stride + (x-Rect.Min.X)*4].
+	Pix []float32
+	// Stride is the Pix stride between vertically adjacent pixels.
+	Stride int
+	// Rect is the image's bounds.
+	Rect image.Rectangle
+}
+
+// NewXYZA returns a new HDR XYZA image with the given bounds.
+func NewXYZA(r image.Rectangle) *XYZA {
+	w, h := r.Dx(), r.Dy()
+	buf := make([]float32, 4*w*h)
+	return &XYZA{buf, 4 * w, r}
+}
+
+// ColorModel implements Image.
+func (p *XYZA) ColorModel() color.Model { return hdrcolor.XYZAModel }
+
+// Bounds implements Image.
+func (p *XYZA) Bounds() image.Rectangle { return p.Rect }
+
+// Size implements Image.
+func (p *XYZA) Size() int {
+	return p.Bounds().Dx() * p.Bounds().Dy()
+}
+
+// At implements Image.
+func (p *XYZA) At(x, y int) color.Color {
+	return p.XYZAAt(x, y)
+}
+
+// HDRAt implements Image.
+func (p *XYZA) HDRAt(x, y int) hdrcolor.Color {
+	return p.XYZAAt(x, y)
+}
+
+// XYZAAt returns the XYZA color at this coordinate.
+func (p *XYZA) XYZAAt(x, y int) hdrcolor.XYZA {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return hdrcolor.XYZA{}
+	}
+	i := p.PixOffset(x, y)
+	return hdrcolor.XYZA{
+		X: float64(p.Pix[i+0]),
+		Y: float64(p.Pix[i+1]),
+		Z: float64(p.Pix[i+2]),
+		A: float64(p.Pix[i+3]),
+	}
+}
+
+// PixOffset returns the index of the first element of Pix that corresponds to
+// the pixel at (x, y).
+func (p *XYZA) PixOffset(x, y int) int {
+	return (y-p.Rect.Min.Y)*p.Stride + (x-p.Rect.Min.X)*4
+}
+
+// Set implements Image.
+func (p *XYZA) Set(x, y int, c color.Color) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	p.SetXYZA(x, y, hdrcolor.XYZAModel.Convert(c).(hdrcolor.XYZA))
+}
+
+// SetXYZA applies the given XYZA color at this coordinate.
+func (p *XYZA) SetXYZA(x, y int, c hdrcolor.XYZA) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	i := p.PixOffset(x, y)
+	p.Pix[i+0] = float32(c.X)
+	p.Pix[i+1] = float32(c.Y)
+	p.Pix[i+2] = float32(c.Z)
+	p.Pix[i+3] = float32(c.A)
+}
+
+// SubImage returns an image representing the portion of the image p visible
+// through r. The returned value shares pixels with the original image.
+func (p *XYZA) SubImage(r image.Rectangle) Image {
+	r = r.Intersect(p.Rect)
+	// If r1 and r2 are Rectangles, r1.Intersect(r2) is not guaranteed to be inside
+	// either r1 or r2 if the intersection is empty. Without explicitly checking for
+	// this, the Pix[i:] expression below can panic.
+	if r.Empty() {
+		return &XYZA{}
+	}
+	i := p.PixOffset(r.Min.X, r.Min.Y)
+	return &XYZA{
+		Pix:    p.Pix[i:],
+		Stride: p.Stride,
+		Rect:   r,
+	}
+}
+
+//===============//
+// RGBA64        //
+//===============//
+
+// RGBA64 is an in-memory 64 bits floating points image whose At method returns hdrcolor.RGBA values.
+// The colors are not alpha-premultiplied.
+type RGBA64 struct {
+	// Pix holds the image's pixels, in R, G, B, A order. The pixel at
+	// (x, y) starts at Pix[(y-Rect.Min.Y)*Stride + (x-Rect.Min.X)*4].
+	Pix []float64
+	// Stride is the Pix stride between vertically adjacent pixels.
+	Stride int
+	// Rect is the image's bounds.
+	Rect image.Rectangle
+	// Space is the color space of the linear pixels, nil for Rec.709 (see ColorSpace).
+	Space *hdrcolor.ColorSpace
+}
+
+// NewRGBA64 returns a new HDR RGBA64 image with the given bounds.
+func NewRGBA64(r image.Rectangle) *RGBA64 {
+	w, h := r.Dx(), r.Dy()
+	buf := make([]float64, 4*w*h)
+	return &RGBA64{Pix: buf, Stride: 4 * w, Rect: r}
+}
+
+// ColorModel implements Image.
+func (p *RGBA64) ColorModel() color.Model { return hdrcolor.RGBAModel }
+
+// Bounds implements Image.
+func (p *RGBA64) Bounds() image.Rectangle { return p.Rect }
+
+// Size implements Image.
+func (p *RGBA64) Size() int {
+	return p.Bounds().Dx() * p.Bounds().Dy()
+}
+
+// At implements Image.
+func (p *RGBA64) At(x, y int) color.Color {
+	return p.RGBAAt(x, y)
+}
+
+// HDRAt implements Image.
+func (p *RGBA64) HDRAt(x, y int) hdrcolor.Color {
+	return p.RGBAAt(x, y)
+}
+
+// RGBAAt returns the RGBA color at this coordinate.
+func (p *RGBA64) RGBAAt(x, y int) hdrcolor.RGBA {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return hdrcolor.RGBA{}
+	}
+	i := p.PixOffset(x, y)
+	return hdrcolor.RGBA{
+		R:     p.Pix[i+0],
+		G:     p.Pix[i+1],
+		B:     p.Pix[i+2],
+		A:     p.Pix[i+3],
+		Space: p.Space,
+	}
+}
+
+// PixOffset returns the index of the first element of Pix that corresponds to
+// the pixel at (x, y).
+func (p *RGBA64) PixOffset(x, y int) int {
+	return (y-p.Rect.Min.Y)*p.Stride + (x-p.Rect.Min.X)*4
+}
+
+// Set implements Image. The color is converted to the color space of the image.
+func (p *RGBA64) Set(x, y int, c color.Color) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	p.SetRGBA(x, y, rgbaIn(p.Space, c))
+}
+
+// SetRGBA applies the given RGBA color at this coordinate.
+// Its values are stored as they are, in the color space of the image.
+func (p *RGBA64) SetRGBA(x, y int, c hdrcolor.RGBA) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	i := p.PixOffset(x, y)
+	p.Pix[i+0] = c.R
+	p.Pix[i+1] = c.G
+	p.Pix[i+2] = c.B
+	p.Pix[i+3] = c.A
+}
+
+// SubImage returns an image representing the portion of the image p visible
+// through r. The returned value shares pixels with the original image.
+func (p *RGBA64) SubImage(r image.Rectangle) Image {
+	r = r.Intersect(p.Rect)
+	// If r1 and r2 are Rectangles, r1.Intersect(r2) is not guaranteed to be inside
+	// either r1 or r2 if the intersection is empty. Without explicitly checking for
+	// this, the Pix[i:] expression below can panic.
+	if r.Empty() {
+		return &RGBA64{}
+	}
+	i := p.PixOffset(r.Min.X, r.Min.Y)
+	return &RGBA64{
+		Pix:    p.Pix[i:],
+		Stride: p.Stride,
+		Rect:   r,
+		Space:  p.Space,
+	}
+}
+
+//===============//
+// XYZA64        //
+//===============//
+
+// XYZA64 is an in-memory 64 bits floating points image whose At method returns hdrcolor.XYZA values.
+// The colors are not alpha-premultiplied.
+type XYZA64 struct {
+	// Pix holds the image's pixels, in X, Y, Z, A order. The pixel at
+	// (x, y) starts at Pix[(y-Rect.Min.Y)*Stride + (x-Rect.Min.X)*4].
+	Pix []float64
+	// Stride is the Pix stride between vertically adjacent pixels.
+	Stride int
+	// Rect is the image's bounds.
+	Rect image.Rectangle
+}
+
+// NewXYZA64 returns a new HDR XYZA64 image with the given bounds.
+func NewXYZA64(r image.Rectangle) *XYZA64 {
+	w, h := r.Dx(), r.Dy()
+	buf := make([]float64, 4*w*h)
+	return &XYZA64{buf, 4 * w, r}
+}
+
+// ColorModel implements Image.
+func (p *XYZA64) ColorModel() color.Model { return hdrcolor.XYZAModel }
+
+// Bounds implements Image.
+func (p *XYZA64) Bounds() image.Rectangle { return p.Rect }
+
+// Size implements Image.
+func (p *XYZA64) Size() int {
+	return p.Bounds().Dx() * p.Bounds().Dy()
+}
+
+// At implements Image.
+func (p *XYZA64) At(x, y int) color.Color {
+	return p.XYZAAt(x, y)
+}
+
+// HDRAt implements Image.
+func (p *XYZA64) HDRAt(x, y int) hdrcolor.Color {
+	return p.XYZAAt(x, y)
+}
+
+// XYZAAt returns the XYZA color at this coordinate.
+func (p *XYZA64) XYZAAt(x, y int) hdrcolor.XYZA {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return hdrcolor.XYZA{}
+	}
+	i := p.PixOffset(x, y)
+	return hdrcolor.XYZA{
+		X: p.Pix[i+0],
+		Y: p.Pix[i+1],
+		Z: p.Pix[i+2],
+		A: p.Pix[i+3],
+	}
+}
+
+// PixOffset returns the index of the first element of Pix that corresponds to
+// the pixel at (x, y).
+func (p *XYZA64) PixOffset(x, y int) int {
+	return (y-p.Rect.Min.Y)*p.Stride + (x-p.Rect.Min.X)*4
+}
+
+// Set implements Image.
+func (p *XYZA64) Set(x, y int, c color.Color) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	p.SetXYZA(x, y, hdrcolor.XYZAModel.Convert(c).(hdrcolor.XYZA))
+}
+
+// SetXYZA applies the given XYZA color at this coordinate.
+func (p *XYZA64) SetXYZA(x, y int, c hdrcolor.XYZA) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	i := p.PixOffset(x, y)
+	p.Pix[i+0] = c.X
+	p.Pix[i+1] = c.Y
+	p.Pix[i+2] = c.Z
+	p.Pix[i+3] = c.A
+}
+
+// SubImage returns an image representing the portion of the image p visible
+// through r. The returned value shares pixels with the original image.
+func (p *XYZA64) SubImage(r image.Rectangle) Image {
+	r = r.Intersect(p.Rect)
+	// If r1 and r2 are Rectangles, r1.Intersect(r2) is not guaranteed to be inside
+	// either r1 or r2 if the intersection is empty. Without explicitly checking for
+	// this, the Pix[i:] expression below can panic.
+	if r.Empty() {
+		return &XYZA64{}
+	}
+	i := p.PixOffset(r.Min.X, r.Min.Y)
+	return &XYZA64{
+		Pix:    p.Pix[i:],
+		Stride: p.Stride,
+		Rect:   r,
+	}
+}
+
+//===============//
+// RGB16F        //
+//===============//
+
+// RGB16F is an in-memory 16 bits (half-precision) floating points image whose At method returns hdrcolor.RGB values.
+// It halves the memory of RGB with a relative accuracy of about 0.05% and a range of ±65504.
+type RGB16F struct {
+	// Pix holds the image's pixels as IEEE 754 half floats, in R, G, B order. The pixel at
+	// (x, y) starts at Pix[(y-Rect.Min.Y)*Stride + (x-Rect.Min.X)*3].
+	Pix []uint16
+	// Stride is the Pix stride between vertically adjacent pixels.
+	Stride int
+	// Rect is the image's bounds.
+	Rect image.Rectangle
+	// Space is the color space of the linear pixels, nil for Rec.709 (see ColorSpace).
+	Space *hdrcolor.ColorSpace
+}
+
+// NewRGB16F returns a new HDR RGB16F image with the given bounds.
+func NewRGB16F(r image.Rectangle) *RGB16F {
+	w, h := r.Dx(), r.Dy()
+	buf := make([]uint16, 3*w*h)
+	return &RGB16F{Pix: buf, Stride: 3 * w, Rect: r}
+}
+
+// ColorModel implements Image.
+func (p *RGB16F) ColorModel() color.Model { return hdrcolor.RGBModel }
+
+// Bounds implements Image.
+func (p *RGB16F) Bounds() image.Rectangle { return p.Rect }
+
+// Size implements Image.
+func (p *RGB16F) Size() int {
+	return p.Bounds().Dx() * p.Bounds().Dy()
+}
+
+// At implements Image.
+func (p *RGB16F) At(x, y int) color.Color {
+	return p.RGBAt(x, y)
+}
+
+// HDRAt implements Image.
+func (p *RGB16F) HDRAt(x, y int) hdrcolor.Color {
+	return p.RGBAt(x, y)
+}
+
+// RGBAt returns the RGB color at this coordinate.
+func (p *RGB16F) RGBAt(x, y int) hdrcolor.RGB {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return hdrcolor.RGB{}
+	}
+	i := p.PixOffset(x, y)
+	return hdrcolor.RGB{
+		R:     float64(format.HalfToFloat(p.Pix[i+0])),
+		G:     float64(format.HalfToFloat(p.Pix[i+1])),
+		B:     float64(format.HalfToFloat(p.Pix[i+2])),
+		Space: p.Space,
+	}
+}
+
+// PixOffset returns the index of the first element of Pix that corresponds to
+// the pixel at (x, y).
+func (p *RGB16F) PixOffset(x, y int) int {
+	return (y-p.Rect.Min.Y)*p.Stride + (x-p.Rect.Min.X)*3
+}
+
+// Set implements Image. The color is converted to the color space of the image.
+func (p *RGB16F) Set(x, y int, c color.Color) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	p.SetRGB(x, y, p.Space.From(hdrcolor.RGBModel.Convert(c).(hdrcolor.RGB)))
+}
+
+// SetRGB applies the given RGB color at this coordinate.
+// Its values are stored as they are, in the color space of the image.
+// Values are rounded to the nearest half float, overflows become infinities.
+func (p *RGB16F) SetRGB(x, y int, c hdrcolor.RGB) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	i := p.PixOffset(x, y)
+	p.Pix[i+0] = format.FloatToHalf(float32(c.R))
+	p.Pix[i+1] = format.FloatToHalf(float32(c.G))
+	p.Pix[i+2] = format.FloatToHalf(float32(c.B))
+}
+
+// SubImage returns an image representing the portion of the image p visible
+// through r. The returned value shares pixels with the original image.
+func (p *RGB16F) SubImage(r image.Rectangle) Image {
+	r = r.Intersect(p.Rect)
+	// If r1 and r2 are Rectangles, r1.Intersect(r2) is not guaranteed to be inside
+	// either r1 or r2 if the intersection is empty. Without explicitly checking for
+	// this, the Pix[i:] expression below can panic.
+	if r.Empty() {
+		return &RGB16F{}
+	}
+	i := p.PixOffset(r.Min.X, r.Min.Y)
+	return &RGB16F{
+		Pix:    p.Pix[i:],
+		Stride: p.Stride,
+		Rect:   r,
+		Space:  p.Space,
+	}
+}
+
+//===============//
+// XYZ16F        //
+//===============//
+
+// XYZ16F is an in-memory 16 bits (half-precision) floating points image whose At method returns hdrcolor.XYZ values.
+// It halves the memory of XYZ with a relative accuracy of about 0.05% and a range of ±65504.
+type XYZ16F struct {
+	// Pix holds the image's pixels as IEEE 754 half floats, in X, Y, Z order. The pixel at
+	// (x, y) starts at Pix[(y-Rect.Min.Y)*Stride + (x-Rect.Min.X)*3].
+	Pix []uint16
+	// Stride is the Pix stride between vertically adjacent pixels.
+	Stride int
+	// Rect is the image's bounds.
+	Rect image.Rectangle
+}
+
+// NewXYZ16F returns a new HDR XYZ16F image with the given bounds.
+func NewXYZ16F(r image.Rectangle) *XYZ16F {
+	w, h := r.Dx(), r.Dy()
+	buf := make([]uint16, 3*w*h)
+	return &XYZ16F{buf, 3 * w, r}
+}
+
+// ColorModel implements Image.
+func (p *XYZ16F) ColorModel() color.Model { return hdrcolor.XYZModel }
+
+// Bounds implements Image.
+func (p *XYZ16F) Bounds() image.Rectangle { return p.Rect }
+
+// Size implements Image.
+func (p *XYZ16F) Size() int {
+	return p.Bounds().Dx() * p.Bounds().Dy()
+}
+
+// At implements Image.
+func (p *XYZ16F) At(x, y int) color.Color {
+	return p.XYZAt(x, y)
+}
+
+// HDRAt implements Image.
+func (p *XYZ16F) HDRAt(x, y int) hdrcolor.Color {
+	return p.XYZAt(x, y)
+}
+
+// XYZAt returns the XYZ color at this coordinate.
+func (p *XYZ16F) XYZAt(x, y int) hdrcolor.XYZ {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return hdrcolor.XYZ{}
+	}
+	i := p.PixOffset(x, y)
+	return hdrcolor.XYZ{
+		X: float64(format.HalfToFloat(p.Pix[i+0])),
+		Y: float64(format.HalfToFloat(p.Pix[i+1])),
+		Z: float64(format.HalfToFloat(p.Pix[i+2])),
+	}
+}
+
+// PixOffset returns the index of the first element of Pix that corresponds to
+// the pixel at (x, y).
+func (p *XYZ16F) PixOffset(x, y int) int {
+	return (y-p.Rect.Min.Y)*p.Stride + (x-p.Rect.Min.X)*3
+}
+
+// Set implements Image.
+func (p *XYZ16F) Set(x, y int, c color.Color) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	p.SetXYZ(x, y, hdrcolor.XYZModel.Convert(c).(hdrcolor.XYZ))
+}
+
+// SetXYZ applies the given XYZ color at this coordinate.
+// Values are rounded to the nearest half float, overflows become infinities.
+func (p *XYZ16F) SetXYZ(x, y int, c hdrcolor.XYZ) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	i := p.PixOffset(x, y)
+	p.Pix[i+0] = format.FloatToHalf(float32(c.X))
+	p.Pix[i+1] = format.FloatToHalf(float32(c.Y))
+	p.Pix[i+2] = format.FloatToHalf(float32(c.Z))
+}
+
+// SubImage returns an image representing the portion of the image p visible
+// through r. The returned value shares pixels with the original image.
+func (p *XYZ16F) SubImage(r image.Rectangle) Image {
+	r = r.Intersect(p.Rect)
+	// If r1 and r2 are Rectangles, r1.Intersect(r2) is not guaranteed to be inside
+	// either r1 or r2 if the intersection is empty. Without explicitly checking for
+	// this, the Pix[i:] expression below can panic.
+	if r.Empty() {
+		return &XYZ16F{}
+	}
+	i := p.PixOffset(r.Min.X, r.Min.Y)
+	return &XYZ16F{
+		Pix:    p.Pix[i:],
+		Stride: p.Stride,
+		Rect:   r,
 	}
 }
@@ -0,0 +1,157 @@
+package pfm
+
+// Resources:
+// http://www.pauldebevec.com/Research/HDR/PFM/
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"math"
+
+	"github.com/Xyzyx101/hdr"
+	"github.com/Xyzyx101/hdr/hdrcolor"
+)
+
+// maxSize avoids huge allocations on corrupted headers.
+const maxSize = 1 << 30
+
+type decoder struct {
+	r           io.Reader
+	config      image.Config
+	nbOfchannel int
+	order       binary.ByteOrder
+}
+
+func newDecoder(r io.Reader) (*decoder, error) {
+	d := &decoder{
+		r: bufio.NewReader(r),
+	}
+
+	return d, d.parseHeader()
+}
+
+//--------------------------------------//
+// Header parser                        //
+//--------------------------------------//
+
+func (d *decoder) parseHeader() error {
+	magic, err := readToken(d.r)
+	if err != nil {
+		return err
+	}
+	switch magic {
+	case headerColor:
+		d.nbOfchannel = 3
+	case headerGrayscale:
+		d.nbOfchannel = 1
+	default:
+		return FormatError("format not compatible")
+	}
+	d.config.ColorModel = hdrcolor.RGBModel
+
+	// Image size
+	for _, v := range []*int{&d.config.Width, &d.config.Height} {
+		token, err := readToken(d.r)
+		if err != nil {
+			return err
+		}
+		if n, err := fmt.Sscanf(token, "%d", v); n < 1 || err != nil {
+			return FormatError("invalid image size specifier")
+		}
+	}
+	if d.config.Width <= 0 || d.config.Height <= 0 {
+		return FormatError("invalid image size")
+	}
+	if d.config.Width > maxSize/d.config.Height/d.nbOfchannel {
+		return FormatError("image too large")
+	}
+
+	// Scale, its sign gives the byte order
+	token, err := readToken(d.r)
+	if err != nil {
+		return err
+	}
+	var scale float64
+	if n, err := fmt.Sscanf(token, "%g", &scale); n < 1 || err != nil || scale == 0 || math.IsNaN(scale) {
+		return FormatError("invalid scale specifier")
+	}
+	d.order = binary.BigEndian
+	if scale < 0 {
+		d.order = binary.LittleEndian
+	}
+
+	return nil
+}
+
+//--------------------------------------//
+// Pixels parser                        //
+//--------------------------------------//
+
+func (d *decoder) decode(dst *hdr.RGB, y int, scanline []byte) {
+	for x := 0; x < d.config.Width; x++ {
+		var c hdrcolor.RGB
+
+		if d.nbOfchannel == 1 {
+			v := d.float(scanline[4*x:])
+			c = hdrcolor.RGB{R: v, G: v, B: v}
+		} else {
+			c = hdrcolor.RGB{
+				R: d.float(scanline[12*x:]),
+				G: d.float(scanline[12*x+4:]),
+				B: d.float(scanline[12*x+8:]),
+			}
+		}
+
+		dst.SetRGB(x, y, c)
+	}
+}
+
+func (d *decoder) float(b []byte) float64 {
+	return float64(math.Float32frombits(d.order.Uint32(b)))
+}
+
+//--------------------------------------//
+// Reader                               //
+//--------------------------------------//
+
+// DecodeConfig returns the color model and dimensions of a PFM image without
+// decoding the entire image.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	d, err := newDecoder(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	return d.config, nil
+}
+
+// Decode reads a PFM image from r and returns an image.Image.
+// Grayscale images are decoded with the same value on each channel.
+// The absolute value of the scale is not applied on the pixels.
+func Decode(r io.Reader) (img image.Image, err error) {
+	d, err := newDecoder(r)
+	if err != nil {
+		return nil, err
+	}
+
+	m := hdr.NewRGB(image.Rect(0, 0, d.config.Width, d.config.Height))
+	scanline := make([]byte, d.config.Width*d.nbOfchannel*4)
+
+	// Rows are stored from bottom to top
+	for y := d.config.Height - 1; y >= 0; y-- {
+		if _, err = io.ReadFull(d.r, scanline); err != nil {
+			return
+		}
+
+		d.decode(m, y, scanline)
+	}
+
+	return m, nil
+}
+
+func init() {
+	image.RegisterFormat("pfm", headerColor, Decode, DecodeConfig)
+	image.RegisterFormat("pfm", headerGrayscale, Decode, DecodeConfig)
+}
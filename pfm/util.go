@@ -0,0 +1,56 @@
+package pfm
+
+import (
+	"bytes"
+	"io"
+)
+
+const (
+	headerColor     = "PF"
+	headerGrayscale = "Pf"
+)
+
+// readToken reads the next whitespace separated token.
+// The whitespace that ends the token is consumed.
+func readToken(r io.Reader) (string, error) {
+	buf := &bytes.Buffer{}
+	p := make([]byte, 1)
+
+	for {
+		if _, err := r.Read(p); err != nil {
+			return "", err
+		}
+
+		if !isSpace(p[0]) {
+			buf.Write(p)
+		} else if buf.Len() > 0 {
+			return buf.String(), nil
+		}
+	}
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\v' || b == '\f'
+}
+
+// A FormatError reports that the input is not a valid PFM image.
+type FormatError string
+
+func (e FormatError) Error() string {
+	return "pfm: invalid format: " + string(e)
+}
+
+// An UnsupportedError reports that the input uses a valid but
+// unimplemented feature.
+type UnsupportedError string
+
+func (e UnsupportedError) Error() string {
+	return "pfm: unsupported feature: " + string(e)
+}
+
+// An InternalError reports that an internal error was encountered.
+type InternalError string
+
+func (e InternalError) Error() string {
+	return "pfm: internal error: " + string(e)
+}
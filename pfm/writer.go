@@ -0,0 +1,117 @@
+package pfm
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/Xyzyx101/hdr"
+)
+
+// Options are the encoding parameters.
+type Options struct {
+	// Grayscale writes only the luminance of the image (Pf variant).
+	Grayscale bool
+	// BigEndian writes a big endian raster instead of a little endian one.
+	BigEndian bool
+}
+
+type encoder struct {
+	w     io.Writer
+	m     hdr.Image
+	o     *Options
+	order binary.ByteOrder
+}
+
+func newEncoder(w io.Writer, m hdr.Image, o *Options) *encoder {
+	e := &encoder{
+		w:     w,
+		m:     m,
+		o:     o,
+		order: binary.LittleEndian,
+	}
+	if o.BigEndian {
+		e.order = binary.BigEndian
+	}
+
+	return e
+}
+
+//--------------------------------------//
+// Header writer                        //
+//--------------------------------------//
+
+func (e *encoder) writeHeader(w io.Writer) error {
+	magic := headerColor
+	if e.o.Grayscale {
+		magic = headerGrayscale
+	}
+
+	scale := "-1.0"
+	if e.o.BigEndian {
+		scale = "1.0"
+	}
+
+	d := e.m.Bounds().Size()
+	_, err := fmt.Fprintf(w, "%s\n%d %d\n%s\n", magic, d.X, d.Y, scale)
+	return err
+}
+
+//--------------------------------------//
+// Pixels writer                        //
+//--------------------------------------//
+
+func (e *encoder) encode() error {
+	w := bufio.NewWriter(e.w)
+	if err := e.writeHeader(w); err != nil {
+		return err
+	}
+
+	d := e.m.Bounds()
+	channels := 3
+	if e.o.Grayscale {
+		channels = 1
+	}
+	row := make([]byte, 4*channels*d.Dx())
+
+	// Rows are stored from bottom to top
+	for y := d.Max.Y - 1; y >= d.Min.Y; y-- {
+		i := 0
+		for x := d.Min.X; x < d.Max.X; x++ {
+			if e.o.Grayscale {
+				_, lum, _, _ := e.m.HDRAt(x, y).HDRXYZA()
+				e.order.PutUint32(row[i:], math.Float32bits(float32(lum)))
+				i += 4
+				continue
+			}
+
+			r, g, b, _ := e.m.HDRAt(x, y).HDRRGBA()
+			e.order.PutUint32(row[i:], math.Float32bits(float32(r)))
+			e.order.PutUint32(row[i+4:], math.Float32bits(float32(g)))
+			e.order.PutUint32(row[i+8:], math.Float32bits(float32(b)))
+			i += 12
+		}
+
+		if _, err := w.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+// Encode writes the Image m to w in PFM color format (little endian).
+func Encode(w io.Writer, m hdr.Image) error {
+	return EncodeWithOptions(w, m, &Options{})
+}
+
+// EncodeWithOptions writes the Image m to w in PFM format.
+func EncodeWithOptions(w io.Writer, m hdr.Image, o *Options) error {
+	if m.Bounds().Empty() {
+		return FormatError("empty image")
+	}
+
+	return newEncoder(w, m, o).encode()
+}
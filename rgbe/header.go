@@ -0,0 +1,208 @@
+package rgbe
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/Xyzyx101/hdr/hdrcolor"
+)
+
+// A Header holds the metadata of a Radiance image.
+// Missing values are left to their zero value, except Exposure and PixelAspect that default to 1.
+type Header struct {
+	// Format is the pixels encoding, FormatRGBE or FormatXYZE.
+	// When encoding, an empty Format is picked from the color model of the image.
+	Format string
+	// Exposure is the product of all the EXPOSURE lines.
+	// Decoded pixels are divided by it and encoded pixels are multiplied by it,
+	// so that pixels always hold the original radiance values.
+	Exposure float64
+	// ColorCorrection is the product of all the COLORCORR lines (R, G, B multipliers).
+	// It is not applied to the pixels.
+	ColorCorrection []float64
+	// Primaries are the CIE (x, y) chromaticities of the red, green, blue and white points.
+	Primaries []float64
+	// PixelAspect is the product of all the PIXASPECT lines (pixel height over width).
+	PixelAspect float64
+	// View holds the view options, VIEW lines are concatenated.
+	View string
+	// Gamma is the GAMMA value.
+	Gamma float64
+	// Software is the program that produced the image.
+	Software string
+	// CaptureDate is the CAPDATE value (YYYY:MM:DD HH:MM:SS).
+	CaptureDate string
+	// Comments holds, in order, all the other lines such as commands history,
+	// commented lines and unknown variables.
+	Comments []string
+	// Orientation is the pixels ordering of the file.
+	// Decoded images are always reoriented to top-left.
+	Orientation Orientation
+}
+
+// NewHeader returns a Header with default values.
+func NewHeader() *Header {
+	return &Header{
+		Exposure:    1,
+		PixelAspect: 1,
+	}
+}
+
+// mode returns the encoding mode of an image of the color model cm.
+// Color models other than hdrcolor.XYZModel are encoded in RGBE.
+func (h *Header) mode(cm color.Model) (imageMode, error) {
+	switch h.Format {
+	case FormatRGBE:
+		return mRGBE, nil
+	case FormatXYZE:
+		return mXYZE, nil
+	case "":
+		if cm == hdrcolor.XYZModel {
+			return mXYZE, nil
+		}
+		return mRGBE, nil
+	}
+
+	return 0, UnsupportedError("format " + h.Format)
+}
+
+// parseLine appends the given header line to the metadata.
+func (h *Header) parseLine(token string) error {
+	i := strings.IndexByte(token, '=')
+	if strings.HasPrefix(token, "#") || i < 0 {
+		h.Comments = append(h.Comments, token)
+		return nil
+	}
+
+	value := strings.TrimSpace(token[i+1:])
+	switch token[:i] {
+	case "EXPOSURE":
+		f, err := parseFloats(value, 1)
+		if err != nil {
+			return FormatError("invalid exposure specifier")
+		}
+		h.Exposure *= f[0]
+	case "COLORCORR":
+		f, err := parseFloats(value, 3)
+		if err != nil {
+			return FormatError("invalid color correction specifier")
+		}
+		if h.ColorCorrection == nil {
+			h.ColorCorrection = []float64{1, 1, 1}
+		}
+		for c := range f {
+			h.ColorCorrection[c] *= f[c]
+		}
+	case "PRIMARIES":
+		f, err := parseFloats(value, 8)
+		if err != nil {
+			return FormatError("invalid primaries specifier")
+		}
+		h.Primaries = f
+	case "PIXASPECT":
+		f, err := parseFloats(value, 1)
+		if err != nil {
+			return FormatError("invalid pixel aspect specifier")
+		}
+		h.PixelAspect *= f[0]
+	case "VIEW":
+		if h.View != "" {
+			h.View += " "
+		}
+		h.View += value
+	case "GAMMA":
+		f, err := parseFloats(value, 1)
+		if err != nil {
+			return FormatError("invalid gamma specifier")
+		}
+		h.Gamma = f[0]
+	case "SOFTWARE":
+		h.Software = value
+	case "CAPDATE":
+		h.CaptureDate = value
+	default:
+		h.Comments = append(h.Comments, token)
+	}
+
+	return nil
+}
+
+// writeTo writes the metadata lines, FORMAT line excluded.
+func (h *Header) writeTo(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	for _, c := range h.Comments {
+		fmt.Fprintln(bw, c)
+	}
+	if h.Exposure != 0 && h.Exposure != 1 {
+		fmt.Fprintf(bw, "EXPOSURE=%s\n", formatFloats(h.Exposure))
+	}
+	if len(h.ColorCorrection) == 3 {
+		fmt.Fprintf(bw, "COLORCORR=%s\n", formatFloats(h.ColorCorrection...))
+	}
+	if len(h.Primaries) == 8 {
+		fmt.Fprintf(bw, "PRIMARIES=%s\n", formatFloats(h.Primaries...))
+	}
+	if h.PixelAspect != 0 && h.PixelAspect != 1 {
+		fmt.Fprintf(bw, "PIXASPECT=%s\n", formatFloats(h.PixelAspect))
+	}
+	if h.View != "" {
+		fmt.Fprintf(bw, "VIEW=%s\n", h.View)
+	}
+	if h.Gamma != 0 {
+		fmt.Fprintf(bw, "GAMMA=%s\n", formatFloats(h.Gamma))
+	}
+	if h.Software != "" {
+		fmt.Fprintf(bw, "SOFTWARE=%s\n", h.Software)
+	}
+	if h.CaptureDate != "" {
+		fmt.Fprintf(bw, "CAPDATE=%s\n", h.CaptureDate)
+	}
+
+	return bw.Flush()
+}
+
+// parseFloats parses exactly n whitespace-separated floats.
+func parseFloats(s string, n int) ([]float64, error) {
+	fields := strings.Fields(s)
+	if len(fields) != n {
+		return nil, FormatError("invalid number of values")
+	}
+
+	f := make([]float64, n)
+	for i, field := range fields {
+		var err error
+		if f[i], err = strconv.ParseFloat(field, 64); err != nil {
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+func formatFloats(f ...float64) string {
+	s := make([]string, len(f))
+	for i := range f {
+		s[i] = strconv.FormatFloat(f[i], 'g', -1, 64)
+	}
+	return strings.Join(s, " ")
+}
+
+// ColorSpace returns the linear color space defined by the PRIMARIES, nil when they are missing.
+func (h *Header) ColorSpace() *hdrcolor.ColorSpace {
+	if len(h.Primaries) != 8 {
+		return nil
+	}
+
+	p := h.Primaries
+	return hdrcolor.NewColorSpace("",
+		hdrcolor.Chromaticity{X: p[0], Y: p[1]},
+		hdrcolor.Chromaticity{X: p[2], Y: p[3]},
+		hdrcolor.Chromaticity{X: p[4], Y: p[5]},
+		hdrcolor.Chromaticity{X: p[6], Y: p[7]},
+		nil)
+}
@@ -0,0 +1,123 @@
+package rgbe
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// An Orientation is the pixels ordering given by the resolution string of the image.
+// Names follow the TIFF convention: the first word is the side of the image stored
+// by the first scanline, the second word is the side of its first pixel.
+type Orientation int
+
+const (
+	// OrientationTopLeft for "-Y height +X width", the standard orientation
+	OrientationTopLeft Orientation = iota
+	// OrientationTopRight for "-Y height -X width"
+	OrientationTopRight
+	// OrientationBottomRight for "+Y height -X width"
+	OrientationBottomRight
+	// OrientationBottomLeft for "+Y height +X width"
+	OrientationBottomLeft
+	// OrientationLeftTop for "+X width -Y height", scanlines are columns
+	OrientationLeftTop
+	// OrientationRightTop for "-X width -Y height", scanlines are columns
+	OrientationRightTop
+	// OrientationRightBottom for "-X width +Y height", scanlines are columns
+	OrientationRightBottom
+	// OrientationLeftBottom for "+X width +Y height", scanlines are columns
+	OrientationLeftBottom
+)
+
+// axes of each orientation: major (scanlines progression) and minor (pixels progression) axes.
+var orientationAxes = [...][2]string{
+	OrientationTopLeft:     {"-Y", "+X"},
+	OrientationTopRight:    {"-Y", "-X"},
+	OrientationBottomRight: {"+Y", "-X"},
+	OrientationBottomLeft:  {"+Y", "+X"},
+	OrientationLeftTop:     {"+X", "-Y"},
+	OrientationRightTop:    {"-X", "-Y"},
+	OrientationRightBottom: {"-X", "+Y"},
+	OrientationLeftBottom:  {"+X", "+Y"},
+}
+
+// Transposed reports whether the scanlines are columns of the image.
+func (o Orientation) Transposed() bool {
+	return o >= OrientationLeftTop
+}
+
+// scanlines returns the number of scanlines and the number of pixels per scanline of an image.
+func (o Orientation) scanlines(width, height int) (count, length int) {
+	if o.Transposed() {
+		return width, height
+	}
+	return height, width
+}
+
+// position returns the top-left based position of the pixel j of the scanline i.
+func (o Orientation) position(i, j, width, height int) (x, y int) {
+	axes := orientationAxes[o]
+
+	x, y = j, i
+	if o.Transposed() {
+		x, y = i, j
+	}
+
+	// Radiance Y axis points up, so -Y goes from the top to the bottom.
+	if axes[0] == "+Y" || axes[1] == "+Y" {
+		y = height - 1 - y
+	}
+	if axes[0] == "-X" || axes[1] == "-X" {
+		x = width - 1 - x
+	}
+
+	return
+}
+
+// resolution returns the resolution string of an image.
+func (o Orientation) resolution(width, height int) string {
+	axes := orientationAxes[o]
+
+	size := func(axis string) int {
+		if axis[1] == 'X' {
+			return width
+		}
+		return height
+	}
+
+	return fmt.Sprintf("%s %d %s %d", axes[0], size(axes[0]), axes[1], size(axes[1]))
+}
+
+// parseResolution parses a resolution string.
+func parseResolution(token string) (o Orientation, width, height int, err error) {
+	fields := strings.Fields(token)
+	if len(fields) != 4 {
+		return 0, 0, 0, FormatError("missing image size specifier")
+	}
+
+	found := false
+	for i, axes := range orientationAxes {
+		if fields[0] == axes[0] && fields[2] == axes[1] {
+			o = Orientation(i)
+			found = true
+			break
+		}
+	}
+	if !found {
+		return 0, 0, 0, FormatError("missing image size specifier")
+	}
+
+	n1, err1 := strconv.Atoi(fields[1])
+	n2, err2 := strconv.Atoi(fields[3])
+	if err1 != nil || err2 != nil || n1 <= 0 || n2 <= 0 {
+		return 0, 0, 0, FormatError("invalid image size")
+	}
+
+	width, height = n2, n1
+	if o.Transposed() {
+		width, height = n1, n2
+	}
+
+	return o, width, height, nil
+}
@@ -7,7 +7,6 @@ package rgbe
 
 import (
 	"bufio"
-	"fmt"
 	"image"
 	"io"
 	"strings"
@@ -19,16 +18,21 @@ import (
 
 type decoder struct {
 	r        io.Reader
+	h        *Header
 	config   image.Config
 	exposure float64
 	mode     imageMode
+	count    int // Number of scanlines
+	length   int // Number of pixels per scanline
 }
 
 func newDecoder(r io.Reader) (*decoder, error) {
 	d := &decoder{
 		r:        bufio.NewReader(r),
+		h:        NewHeader(),
 		exposure: 1.0,
 	}
+	d.config.ColorModel = hdrcolor.RGBModel // Default FORMAT
 
 	return d, d.parseHeader()
 }
@@ -58,6 +62,7 @@ func (d *decoder) parseHeader() error {
 		case "#?AUTOPANO":
 			// Format specifier found (magic number)
 			magic = true
+			continue
 		}
 
 		if err := d.appendHeaderAttributes(token); err != nil {
@@ -67,9 +72,10 @@ func (d *decoder) parseHeader() error {
 NEXT:
 
 	// ignore weird exposure adjustments
-	if d.exposure > 1e12 || d.exposure < 1e-12 {
-		d.exposure = 1.0
+	if d.h.Exposure > 1e12 || d.h.Exposure < 1e-12 {
+		d.h.Exposure = 1.0
 	}
+	d.exposure = d.h.Exposure
 
 	if !magic {
 		return FormatError("format not compatible")
@@ -80,45 +86,41 @@ NEXT:
 	if err != nil {
 		return err
 	}
-	if n, err := fmt.Sscanf(token, "-Y %d +X %d", &d.config.Height, &d.config.Width); n < 2 || err != nil {
-		return FormatError("missing image size specifier")
+	d.h.Orientation, d.config.Width, d.config.Height, err = parseResolution(token)
+	if err != nil {
+		return err
 	}
+	d.count, d.length = d.h.Orientation.scanlines(d.config.Width, d.config.Height)
 
 	return nil
 }
 
 func (d *decoder) appendHeaderAttributes(token string) error {
-	if strings.HasPrefix(token, "#") {
-		// Skip commented line
-		return nil
-	}
-	if token == "FORMAT=32-bit_rle_rgbe" {
+	if token == "FORMAT="+FormatRGBE {
 		// Header found
+		d.h.Format = FormatRGBE
 		d.mode = mRGBE
 		d.config.ColorModel = hdrcolor.RGBModel
 		return nil
 	}
-	if token == "FORMAT=32-bit_rle_xyze" {
+	if token == "FORMAT="+FormatXYZE {
 		// Header found
+		d.h.Format = FormatXYZE
 		d.mode = mXYZE
 		d.config.ColorModel = hdrcolor.XYZModel
 		return nil
 	}
-	if strings.HasPrefix(token, "EXPOSURE=") {
-		if n, err := fmt.Sscanf(token, "EXPOSURE=%f", &d.exposure); n < 1 || err != nil {
-			return FormatError("invalid exposure specifier")
-		}
-	}
 
-	return nil
+	return d.h.parseLine(token)
 }
 
 //--------------------------------------//
 // Pixels parser                        //
 //--------------------------------------//
 
-func (d *decoder) decode(dst image.Image, y int, scanline []byte) {
-	for x := 0; x < d.config.Width; x++ {
+// decode converts a flat scanline to floats triplets.
+func (d *decoder) decode(dst []float32, scanline []byte) {
+	for x := 0; x < d.length; x++ {
 		b0, b1, b2 := format.FromRadianceBytes(
 			scanline[4*x],
 			scanline[4*x+1],
@@ -126,33 +128,60 @@ func (d *decoder) decode(dst image.Image, y int, scanline []byte) {
 			scanline[4*x+3],
 			d.exposure)
 
-		switch d.mode {
-		case mRGBE:
-			img := dst.(*hdr.RGB)
-			img.SetRGB(x, y, hdrcolor.RGB{R: b0, G: b1, B: b2})
-		case mXYZE:
-			img := dst.(*hdr.XYZ)
-			img.SetXYZ(x, y, hdrcolor.XYZ{X: b0, Y: b1, Z: b2})
-		}
+		dst[3*x] = float32(b0)
+		dst[3*x+1] = float32(b1)
+		dst[3*x+2] = float32(b2)
 	}
 }
 
-func (d *decoder) decodeRLE(dst image.Image, y int, scanline []byte) {
-	for x := 0; x < d.config.Width; x++ {
+// decodeRLE converts a per-channel scanline to floats triplets.
+func (d *decoder) decodeRLE(dst []float32, scanline []byte) {
+	for x := 0; x < d.length; x++ {
 		b0, b1, b2 := format.FromRadianceBytes(
 			scanline[x],
-			scanline[x+d.config.Width],
-			scanline[x+d.config.Width*2],
-			scanline[x+d.config.Width*3],
+			scanline[x+d.length],
+			scanline[x+d.length*2],
+			scanline[x+d.length*3],
 			d.exposure)
 
-		switch d.mode {
-		case mRGBE:
-			img := dst.(*hdr.RGB)
-			img.SetRGB(x, y, hdrcolor.RGB{R: b0, G: b1, B: b2})
-		case mXYZE:
-			img := dst.(*hdr.XYZ)
-			img.SetXYZ(x, y, hdrcolor.XYZ{X: b0, Y: b1, Z: b2})
+		dst[3*x] = float32(b0)
+		dst[3*x+1] = float32(b1)
+		dst[3*x+2] = float32(b2)
+	}
+}
+
+// readFlat reads a flat scanline starting with the already read pixel.
+// It expands the original Radiance runs (before 1991) where a 1,1,1,n pixel
+// repeats the previous pixel n times, the count being shifted by 8 bits
+// for each consecutive run pixel.
+func (d *decoder) readFlat(scanline, pixel, prev []byte) error {
+	var shift uint
+
+	for x := 0; ; {
+		if pixel[0] == 1 && pixel[1] == 1 && pixel[2] == 1 {
+			// a run of the previous pixel
+			n := int(pixel[3]) << shift
+			if x+n > d.length {
+				return FormatError("difference in size while reading old RLE scanline")
+			}
+			for ; n > 0; n-- {
+				copy(scanline[4*x:], prev)
+				x++
+			}
+			shift += 8
+		} else {
+			copy(scanline[4*x:], pixel)
+			copy(prev, pixel)
+			x++
+			shift = 0
+		}
+
+		if x == d.length {
+			return nil
+		}
+
+		if _, err := io.ReadFull(d.r, pixel); err != nil {
+			return err
 		}
 	}
 }
@@ -162,14 +191,14 @@ func (d *decoder) readRLE(scanline []byte) (err error) {
 
 	// --- each channel is encoded separately
 	for ch := 0; ch < 4; ch++ {
-		index := d.config.Width * ch
+		index := d.length * ch
 		peek := 0
-		for peek < d.config.Width {
+		for peek < d.length {
 
 			// Read RLE
 			if _, err = io.ReadFull(d.r, buf); err != nil {
 				if err == io.EOF {
-					err = nil
+					err = io.ErrUnexpectedEOF
 				}
 				return
 			}
@@ -177,20 +206,31 @@ func (d *decoder) readRLE(scanline []byte) (err error) {
 			if buf[0] > 128 {
 				// a run of the same value
 				runLength := int(buf[0]) - 128
+				if peek+runLength > d.length {
+					err = FormatError("difference in size while reading RLE scanline")
+					return
+				}
+
 				for ; runLength > 0; runLength-- {
 					scanline[index+peek] = buf[1]
 					peek++
 				}
 			} else {
 				// a non-run
+				nonrunLength := int(buf[0])
+				if nonrunLength == 0 || peek+nonrunLength > d.length {
+					err = FormatError("difference in size while reading RLE scanline")
+					return
+				}
+
 				scanline[index+peek] = buf[1]
 				peek++
+				nonrunLength--
 
-				nonrunLength := int(buf[0]) - 1
 				if nonrunLength > 0 {
 					if _, err = io.ReadFull(d.r, scanline[index+peek:index+peek+nonrunLength]); err != nil {
 						if err == io.EOF {
-							err = nil
+							err = io.ErrUnexpectedEOF
 						}
 						return
 					}
@@ -199,11 +239,6 @@ func (d *decoder) readRLE(scanline []byte) (err error) {
 				}
 			}
 		}
-
-		if peek != d.config.Width {
-			err = FormatError("difference in size while reading RLE scanline")
-			return
-		}
 	}
 
 	return
@@ -223,60 +258,67 @@ func DecodeConfig(r io.Reader) (image.Config, error) {
 	return d.config, nil
 }
 
-// Decode reads a HDR image from r and returns an image.Image.
-func Decode(r io.Reader) (img image.Image, err error) {
+// DecodeHeader returns the metadata of a RGBE image without decoding the entire image.
+func DecodeHeader(r io.Reader) (*Header, error) {
 	d, err := newDecoder(r)
 	if err != nil {
 		return nil, err
 	}
+	return d.h, nil
+}
+
+// Decode reads a HDR image from r and returns an image.Image.
+func Decode(r io.Reader) (image.Image, error) {
+	img, _, err := DecodeWithHeader(r)
+	return img, err
+}
+
+// DecodeWithHeader reads a HDR image from r and returns an image.Image and its metadata.
+// RGBE images are tagged with the color space of the PRIMARIES when present.
+func DecodeWithHeader(r io.Reader) (img image.Image, h *Header, err error) {
+	d, err := newDecoder(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	h = d.h
 
 	imgRect := image.Rect(0, 0, d.config.Width, d.config.Height)
+	var pix []float32
+	var stride int
 	switch d.mode {
 	case mRGBE:
-		img = hdr.NewRGB(imgRect)
+		m := hdr.NewRGB(imgRect)
+		m.Space = h.ColorSpace()
+		img, pix, stride = m, m.Pix, m.Stride
 	case mXYZE:
-		img = hdr.NewXYZ(imgRect)
+		m := hdr.NewXYZ(imgRect)
+		img, pix, stride = m, m.Pix, m.Stride
 	default:
 		err = UnsupportedError("image mode")
 		return
 	}
 
-	scanline := make([]byte, d.config.Width*4) // 4 bytes for one pixel
-	pixel := make([]byte, 4)                   // RGBE pixel
-
-	for y := 0; y < d.config.Height; y++ {
-
-		// Read rle header
-		if _, err = io.ReadFull(d.r, pixel); err != nil {
-			return
-		}
-
-		if pixel[0] != 2 || pixel[1] != 2 || int(pixel[2])<<8|int(pixel[3]) != d.config.Width {
-			// --- simple scanline (not rle)
-
-			var n int
-			n, err = io.ReadFull(d.r, scanline[4:])
-			if err != nil {
-				return
-			}
-
-			if n != (4*d.config.Width - 4) {
-				err = FormatError("not enough data to read in the simple format")
+	s := newScanlineReader(d)
+	if d.h.Orientation == OrientationTopLeft {
+		for y := 0; y < d.config.Height; y++ {
+			if err = s.ReadScanline(pix[y*stride : (y+1)*stride]); err != nil {
 				return
 			}
+		}
 
-			// Restore first read pixel
-			scanline[0], scanline[1], scanline[2], scanline[3] = pixel[0], pixel[1], pixel[2], pixel[3]
-
-			d.decode(img, y, scanline)
-		} else {
-			// --- rle scanline
+		return
+	}
 
-			if err = d.readRLE(scanline); err != nil {
-				return
-			}
+	// Reorient scanlines to top-left
+	scanline := make([]float32, 3*d.length)
+	for i := 0; i < d.count; i++ {
+		if err = s.ReadScanline(scanline); err != nil {
+			return
+		}
 
-			d.decodeRLE(img, y, scanline)
+		for j := 0; j < d.length; j++ {
+			x, y := d.h.Orientation.position(i, j, d.config.Width, d.config.Height)
+			copy(pix[y*stride+3*x:y*stride+3*x+3], scanline[3*j:3*j+3])
 		}
 	}
 
@@ -0,0 +1,198 @@
+package rgbe
+
+import (
+	"bufio"
+	"image"
+	"io"
+
+	"github.com/Xyzyx101/hdr/hdrcolor"
+)
+
+// A ScanlineReader decodes a Radiance RGBE/XYZE image one scanline at a time,
+// so that huge images can be processed with a bounded memory.
+// Scanlines are read in file order as given by Header().Orientation,
+// which are the rows from top to bottom for the standard orientation.
+type ScanlineReader struct {
+	d        *decoder
+	y        int
+	scanline []byte
+	pixel    []byte
+	prev     []byte
+}
+
+// NewScanlineReader reads the header of the image from r and returns a ScanlineReader
+// positioned on the first scanline.
+func NewScanlineReader(r io.Reader) (*ScanlineReader, error) {
+	d, err := newDecoder(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return newScanlineReader(d), nil
+}
+
+func newScanlineReader(d *decoder) *ScanlineReader {
+	return &ScanlineReader{
+		d:        d,
+		scanline: make([]byte, d.length*4), // 4 bytes for one pixel
+		pixel:    make([]byte, 4),          // RGBE pixel
+		prev:     make([]byte, 4),          // Last decoded pixel, repeated by original runs
+	}
+}
+
+// Config returns the color model and dimensions of the image.
+// The color model is hdrcolor.RGBModel for RGBE images and hdrcolor.XYZModel for XYZE images.
+func (s *ScanlineReader) Config() image.Config {
+	return s.d.config
+}
+
+// Header returns the metadata of the image.
+func (s *ScanlineReader) Header() *Header {
+	return s.d.h
+}
+
+// Y returns the index of the next scanline to be read.
+func (s *ScanlineReader) Y() int {
+	return s.y
+}
+
+// ReadScanline decodes the next scanline in dst as R, G, B (or X, Y, Z) floats triplets.
+// dst must hold at least 3*width values (3*height for transposed orientations).
+// It returns io.EOF once all the scanlines have been read.
+func (s *ScanlineReader) ReadScanline(dst []float32) (err error) {
+	d := s.d
+	if s.y >= d.count {
+		return io.EOF
+	}
+	if len(dst) < 3*d.length {
+		return InternalError("scanline buffer too small")
+	}
+
+	// Read rle header
+	if _, err = io.ReadFull(d.r, s.pixel); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return
+	}
+
+	if s.pixel[0] != 2 || s.pixel[1] != 2 || int(s.pixel[2])<<8|int(s.pixel[3]) != d.length {
+		// --- simple scanline (not rle), possibly with original runs
+
+		if err = d.readFlat(s.scanline, s.pixel, s.prev); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return
+		}
+
+		d.decode(dst, s.scanline)
+	} else {
+		// --- rle scanline
+
+		if err = d.readRLE(s.scanline); err != nil {
+			return
+		}
+
+		d.decodeRLE(dst, s.scanline)
+	}
+
+	s.y++
+	return nil
+}
+
+// A ScanlineWriter encodes a Radiance RGBE/XYZE image one scanline at a time,
+// so that huge images can be produced with a bounded memory.
+// Scanlines are written in file order as given by the header's orientation.
+type ScanlineWriter struct {
+	e      *encoder
+	w      *bufio.Writer
+	y      int
+	pixels []byte
+}
+
+// NewScanlineWriter writes the header of an image of the given configuration to w
+// and returns a ScanlineWriter expecting the first scanline.
+// The color model must be hdrcolor.RGBModel (RGBE) or hdrcolor.XYZModel (XYZE).
+func NewScanlineWriter(w io.Writer, config image.Config) (*ScanlineWriter, error) {
+	return NewScanlineWriterWithOptions(w, config, nil)
+}
+
+// NewScanlineWriterWithOptions is like NewScanlineWriter with the metadata of h.
+// The format of h, when set, takes precedence over the color model.
+func NewScanlineWriterWithOptions(w io.Writer, config image.Config, h *Header) (*ScanlineWriter, error) {
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, FormatError("invalid image size")
+	}
+	if h == nil {
+		h = NewHeader()
+	}
+
+	e := &encoder{
+		w:    w,
+		h:    h,
+		size: image.Pt(config.Width, config.Height),
+	}
+
+	if h.Format == "" && config.ColorModel != hdrcolor.RGBModel && config.ColorModel != hdrcolor.XYZModel {
+		return nil, UnsupportedError("color space")
+	}
+
+	var err error
+	if e.mode, err = h.mode(config.ColorModel); err != nil {
+		return nil, err
+	}
+
+	if err := e.writeHeader(); err != nil {
+		return nil, err
+	}
+
+	_, length := e.scanlines()
+	return &ScanlineWriter{
+		e:      e,
+		w:      bufio.NewWriter(w),
+		pixels: make([]byte, 4*length),
+	}, nil
+}
+
+// Y returns the index of the next scanline to be written.
+func (s *ScanlineWriter) Y() int {
+	return s.y
+}
+
+// WriteScanline encodes the next scanline from src as R, G, B (or X, Y, Z) floats triplets.
+// src must hold at least 3*width values (3*height for transposed orientations).
+func (s *ScanlineWriter) WriteScanline(src []float32) error {
+	count, length := s.e.scanlines()
+	if s.y >= count {
+		return InternalError("too many scanlines")
+	}
+	if len(src) < 3*length {
+		return InternalError("scanline buffer too small")
+	}
+
+	for x := 0; x < length; x++ {
+		i := 3 * x
+		copy(s.pixels[4*x:], s.e.toBytes(float64(src[i]), float64(src[i+1]), float64(src[i+2])))
+	}
+
+	if err := s.e.writeScanline(s.w, s.pixels); err != nil {
+		return err
+	}
+
+	s.y++
+	return nil
+}
+
+// Close flushes the buffered data to the underlying writer.
+// It returns an error if some scanlines have not been written.
+func (s *ScanlineWriter) Close() error {
+	if err := s.w.Flush(); err != nil {
+		return err
+	}
+
+	if count, _ := s.e.scanlines(); s.y != count {
+		return InternalError("missing scanlines")
+	}
+	return nil
+}
@@ -13,6 +13,13 @@ const (
 	header2 = "#?AUTOPANO"
 )
 
+const (
+	// FormatRGBE for RGBE pixels
+	FormatRGBE = "32-bit_rle_rgbe"
+	// FormatXYZE for XYZE pixels
+	FormatXYZE = "32-bit_rle_xyze"
+)
+
 // imageMode represents the mode of the image.
 type imageMode int
 
@@ -72,23 +79,62 @@ func readUntil(r io.Reader, delimiter byte) (string, error) {
 	}
 }
 
+// ar gives access to the pixels of an image with 0-based coordinates,
+// as RGB or XYZ values depending on the mode.
 type ar struct {
 	at func(x, y int) (float64, float64, float64)
 }
 
-func newAR(m hdr.Image) *ar {
+func newAR(m hdr.Image, mode imageMode) *ar {
 	s := &ar{}
+	o := m.Bounds().Min
 
 	switch v := m.(type) {
 	case *hdr.RGB:
-		s.at = func(x, y int) (float64, float64, float64) {
-			p := v.RGBAt(x, y)
-			return p.R, p.G, p.B
+		if mode == mRGBE {
+			s.at = func(x, y int) (float64, float64, float64) {
+				p := v.RGBAt(o.X+x, o.Y+y)
+				return p.R, p.G, p.B
+			}
+		}
+	case *hdr.RGB64:
+		if mode == mRGBE {
+			s.at = func(x, y int) (float64, float64, float64) {
+				p := v.RGBAt(o.X+x, o.Y+y)
+				return p.R, p.G, p.B
+			}
 		}
 	case *hdr.XYZ:
+		if mode == mXYZE {
+			s.at = func(x, y int) (float64, float64, float64) {
+				p := v.XYZAt(o.X+x, o.Y+y)
+				return p.X, p.Y, p.Z
+			}
+		}
+	case *hdr.XYZ64:
+		if mode == mXYZE {
+			s.at = func(x, y int) (float64, float64, float64) {
+				p := v.XYZAt(o.X+x, o.Y+y)
+				return p.X, p.Y, p.Z
+			}
+		}
+	}
+
+	if s.at != nil {
+		return s
+	}
+
+	// Generic conversion
+	if mode == mXYZE {
+		s.at = func(x, y int) (float64, float64, float64) {
+			xx, yy, zz, _ := m.HDRAt(o.X+x, o.Y+y).HDRXYZA()
+			return xx, yy, zz
+		}
+	} else {
+		cs := hdr.ColorSpace(m)
 		s.at = func(x, y int) (float64, float64, float64) {
-			p := v.XYZAt(x, y)
-			return p.X, p.Y, p.Z
+			c := cs.From(m.HDRAt(o.X+x, o.Y+y))
+			return c.R, c.G, c.B
 		}
 	}
 
@@ -2,26 +2,36 @@ package rgbe
 
 import (
 	"bufio"
-	"fmt"
+	"image"
 	"io"
 
 	"github.com/Xyzyx101/hdr"
 	"github.com/Xyzyx101/hdr/format"
+	"github.com/Xyzyx101/hdr/hdrcolor"
 )
 
 // RLEWrites allows to write image file with run-length encoding.
 var RLEWrites = true
 
 type encoder struct {
-	w    io.Writer
-	m    hdr.Image
-	mode imageMode
+	w        io.Writer
+	m        hdr.Image
+	h        *Header
+	mode     imageMode
+	size     image.Point
+	scanline []byte
 }
 
-func newEncoder(w io.Writer, m hdr.Image) *encoder {
+func newEncoder(w io.Writer, m hdr.Image, h *Header) *encoder {
+	if h == nil {
+		h = NewHeader()
+	}
+
 	return &encoder{
-		w: w,
-		m: m,
+		w:    w,
+		m:    m,
+		h:    h,
+		size: m.Bounds().Size(),
 	}
 }
 
@@ -35,18 +45,30 @@ func (e *encoder) writeHeader() error {
 		return err
 	}
 
+	// The primaries are the ones of the written pixels, none for Rec.709
+	h := *e.h
+	if e.mode == mRGBE {
+		h.Primaries = nil
+		if cs := hdr.ColorSpace(e.m); cs != nil && cs != hdrcolor.Rec709 && cs != hdrcolor.SRGB {
+			h.Primaries = []float64{cs.Red.X, cs.Red.Y, cs.Green.X, cs.Green.Y, cs.Blue.X, cs.Blue.Y, cs.White.X, cs.White.Y}
+		}
+	}
+
+	if err = h.writeTo(e.w); err != nil {
+		return err
+	}
+
 	switch e.mode {
 	case mRGBE:
-		_, err = io.WriteString(e.w, "FORMAT=32-bit_rle_rgbe\n")
+		_, err = io.WriteString(e.w, "FORMAT="+FormatRGBE+"\n")
 	case mXYZE:
-		_, err = io.WriteString(e.w, "FORMAT=32-bit_rle_xyze\n")
+		_, err = io.WriteString(e.w, "FORMAT="+FormatXYZE+"\n")
 	}
 	if err != nil {
 		return err
 	}
 
-	d := e.m.Bounds().Size()
-	size := fmt.Sprintf("\n-Y %d +X %d\n", d.Y, d.X)
+	size := "\n" + e.h.Orientation.resolution(e.size.X, e.size.Y) + "\n"
 	_, err = io.WriteString(e.w, size)
 
 	return err
@@ -58,66 +80,76 @@ func (e *encoder) writeHeader() error {
 
 func (e *encoder) encode() error {
 	w := bufio.NewWriter(e.w)
-	ar := newAR(e.m)
-
-	d := e.m.Bounds().Size()
-
-	var err error
-	for y := 0; y < d.Y; y++ {
-		for x := 0; x < d.X; x++ {
-			_, err = w.Write(format.ToRadianceBytes(ar.at(x, y)))
+	ar := newAR(e.m, e.mode)
+	count, length := e.scanlines()
+
+	pixels := make([]byte, 4*length)
+	for i := 0; i < count; i++ {
+		for j := 0; j < length; j++ {
+			x, y := e.h.Orientation.position(i, j, e.size.X, e.size.Y)
+			copy(pixels[4*j:], e.toBytes(ar.at(x, y)))
+		}
 
-			if err != nil {
-				return err
-			}
+		if err := e.writeScanline(w, pixels); err != nil {
+			return err
 		}
 	}
 
 	return w.Flush()
 }
 
-func (e *encoder) encodeRLE() error {
-	w := bufio.NewWriter(e.w)
-	ar := newAR(e.m)
-	d := e.m.Bounds().Size()
+// scanlines returns the number of scanlines and the number of pixels per scanline.
+func (e *encoder) scanlines() (count, length int) {
+	return e.h.Orientation.scanlines(e.size.X, e.size.Y)
+}
+
+// toBytes converts the given floats to a RGBE/XYZE pixel, applying the header's exposure.
+func (e *encoder) toBytes(f1, f2, f3 float64) []byte {
+	if ex := e.h.Exposure; ex != 0 && ex != 1 {
+		f1, f2, f3 = f1*ex, f2*ex, f3*ex
+	}
+	return format.ToRadianceBytes(f1, f2, f3)
+}
+
+// writeScanline writes one scanline of RGBE/XYZE pixels (4 bytes per pixel),
+// with run-length encoding when RLEWrites is enabled.
+func (e *encoder) writeScanline(w *bufio.Writer, pixels []byte) error {
+	if !RLEWrites {
+		_, err := w.Write(pixels)
+		return err
+	}
+
+	_, n := e.scanlines()
 
 	// RLE header
-	header := make([]byte, 4)
-	header[0] = 2
-	header[1] = 2
-	header[2] = byte(d.X >> 8)
-	header[3] = byte(d.X & 0xFF)
+	header := []byte{2, 2, byte(n >> 8), byte(n & 0xFF)}
 
-	scanline := make([]byte, d.X*4)
+	if e.scanline == nil {
+		e.scanline = make([]byte, n*4)
+	}
 
-	var err error
-	for y := 0; y < d.Y; y++ {
-		// Prepare RLE treatment for each channel.
-		for x := 0; x < d.X; x++ {
-			pixel := format.ToRadianceBytes(ar.at(x, y))
-			scanline[x] = pixel[0]       // R or X
-			scanline[x+d.X] = pixel[1]   // G or Y
-			scanline[x+2*d.X] = pixel[2] // B or Z
-			scanline[x+3*d.X] = pixel[3] // Exposure
-		}
+	// Prepare RLE treatment for each channel.
+	for x := 0; x < n; x++ {
+		e.scanline[x] = pixels[4*x]       // R or X
+		e.scanline[x+n] = pixels[4*x+1]   // G or Y
+		e.scanline[x+2*n] = pixels[4*x+2] // B or Z
+		e.scanline[x+3*n] = pixels[4*x+3] // Exposure
+	}
 
-		// Append data to the file
-		_, err = w.Write(header)
-		if err != nil {
-			return err
-		}
+	// Append data to the file
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
 
-		for c := 0; c < 4; c++ {
-			// Apply RLE for each channel
-			offset := c * d.X
-			err = e.writeRLE(w, scanline[offset:offset+d.X])
-			if err != nil {
-				return err
-			}
+	for c := 0; c < 4; c++ {
+		// Apply RLE for each channel
+		offset := c * n
+		if err := e.writeRLE(w, e.scanline[offset:offset+n]); err != nil {
+			return err
 		}
 	}
 
-	return w.Flush()
+	return nil
 }
 
 func (e *encoder) writeRLE(w *bufio.Writer, scanline []byte) error {
@@ -192,24 +224,27 @@ func (e *encoder) writeNonRun(w *bufio.Writer, scanline []byte) error {
 }
 
 // Encode writes the Image m to w in RGBE format.
+// Images of the hdrcolor.XYZModel color model are written in XYZE, the others in RGBE.
 func Encode(w io.Writer, m hdr.Image) error {
-	e := newEncoder(w, m)
+	return EncodeWithOptions(w, m, nil)
+}
 
-	switch m.(type) {
-	case *hdr.RGB:
-		e.mode = mRGBE
-	case *hdr.XYZ:
-		e.mode = mXYZE
-	default:
-		return UnsupportedError("color space")
+// EncodeWithOptions writes the Image m to w in RGBE format with the metadata of h,
+// pixels are stored in the format and orientation of h. A nil h writes no metadata.
+func EncodeWithOptions(w io.Writer, m hdr.Image, h *Header) error {
+	if m == nil || m.Bounds().Empty() {
+		return UnsupportedError("empty image")
 	}
 
-	if err := e.writeHeader(); err != nil {
+	e := newEncoder(w, m, h)
+
+	var err error
+	if e.mode, err = e.h.mode(m.ColorModel()); err != nil {
 		return err
 	}
 
-	if RLEWrites {
-		return e.encodeRLE()
+	if err := e.writeHeader(); err != nil {
+		return err
 	}
 
 	return e.encode()
@@ -6,9 +6,9 @@ import (
 	"math"
 	"sync"
 
-	"github.com/mdouchement/hdr"
-	"github.com/mdouchement/hdr/filter"
-	"github.com/mdouchement/hdr/util"
+	"github.com/Xyzyx101/hdr"
+	"github.com/Xyzyx101/hdr/filter"
+	"github.com/Xyzyx101/hdr/util"
 )
 
 const (
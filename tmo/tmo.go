@@ -4,6 +4,10 @@ import (
 	"fmt"
 	"image"
 	"math"
+
+	"github.com/Xyzyx101/hdr"
+	"github.com/Xyzyx101/hdr/filter"
+	"github.com/Xyzyx101/hdr/hdrcolor"
 )
 
 const (
@@ -75,6 +79,16 @@ func Clamp(channel float64) float64 {
 	return channel
 }
 
+// MapGamut returns the image m with its colors converted to Rec.709 and mapped into its gamut with gm,
+// to be given to a TMO (e.g. NewLinear(MapGamut(m, hdrcolor.GamutDesaturate))).
+// m is returned when gm is nil, leaving out-of-gamut colors to the TMO.
+func MapGamut(m hdr.Image, gm *hdrcolor.GamutMapping) hdr.Image {
+	if gm == nil {
+		return m
+	}
+	return filter.NewGamutMapping(m, hdrcolor.Rec709, gm)
+}
+
 // WoB returns 1.0 if b is true, else 0.0
 func WoB(b bool) float64 {
 	if b {
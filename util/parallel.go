@@ -38,3 +38,33 @@ func Parallel(width, height int, f func(x1, y1, x2, y2 int)) chan struct{} {
 
 	return completed
 }
+
+// ParallelN runs f for each index in [0, n) on runtime.NumCPU() goroutines.
+func ParallelN(n int, f func(i int)) chan struct{} {
+	wg := &sync.WaitGroup{}
+	completed := make(chan struct{})
+	indexes := make(chan int)
+
+	for w := 0; w < ncpu && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range indexes {
+				f(i)
+			}
+		}()
+	}
+
+	go func() {
+		for i := 0; i < n; i++ {
+			indexes <- i
+		}
+		close(indexes)
+
+		wg.Wait()
+		close(completed)
+	}()
+
+	return completed
+}